@@ -0,0 +1,44 @@
+package clock
+
+import (
+	"fmt"
+	"time"
+)
+
+// Calendar formats the date of an instant for a clock's date line
+// New calendars (e.g. Persian or Buddhist) are added to the calendars table
+type Calendar func(t time.Time) string
+
+// calendars holds the calendars selectable by name, the empty name is the
+// default Gregorian calendar
+var calendars = map[string]Calendar{
+	"":          Gregorian,
+	"gregorian": Gregorian,
+	"iso-week":  ISOWeekDate,
+}
+
+// CalendarByName returns the calendar with the given name
+func CalendarByName(name string) (Calendar, error) {
+	cal, ok := calendars[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown calendar '%s' (use gregorian or iso-week)", name)
+	}
+	return cal, nil
+}
+
+// Gregorian formats t as YYYY-MM-DD
+func Gregorian(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+// ISOWeekDate formats t as an ISO 8601 week date, e.g. 2025-W49-3
+// The year is the ISO week-numbering year, which differs from the calendar
+// year for some days around New Year
+func ISOWeekDate(t time.Time) string {
+	year, week := t.ISOWeek()
+	weekday := int(t.Weekday())
+	if weekday == 0 {
+		weekday = 7 // ISO weeks start on Monday, Sunday is day 7
+	}
+	return fmt.Sprintf("%04d-W%02d-%d", year, week, weekday)
+}
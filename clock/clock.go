@@ -3,48 +3,256 @@ package clock
 import (
 	"fmt"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
 // Clock represents a world clock for a specific timezone
 type Clock struct {
-	Name     string
-	Location *time.Location
+	Name        string
+	Location    *time.Location
+	Coordinates *Coordinates  // Optional position of the city, nil if unknown
+	Zones       []*Clock      // Labelled times shown together on one card, nil for a single timezone
+	Icon        string        // Optional prefix shown before the title
+	Color       string        // Optional title and border color, an ANSI color index or hex value
+	Short       string        // Optional code for compact output, e.g. "NYC"
+	Calendar    Calendar      // Formats the date line, nil for Gregorian
+	Use12Hour   bool          // Format times on the 12-hour clock with AM/PM
+	Format      string        // Custom time layout (e.g. "15:04"), overrides Use12Hour when set
+	HideWeekday bool          // Leave the weekday out of the date line
+	AbbrevOnly  bool          // Show the zone abbreviation instead of the UTC offset where the zone has one
+	Home        bool          // The user's home clock, other clocks show their offset from it
+	WorkStart   time.Duration // Start of working hours, as time since local midnight
+	WorkEnd     time.Duration // End of working hours, as time since local midnight
+	Frozen      time.Time     // Instant shown instead of the current time, zero while the clock runs
+	DSTWindow   time.Duration // How far ahead NextDSTTransition looks, 0 to not look
+}
+
+// Default working hours
+const (
+	DefaultWorkStart = 9 * time.Hour
+	DefaultWorkEnd   = 17 * time.Hour
+)
+
+// Time layouts
+const (
+	layout24Hour = "15:04:05"
+	layout12Hour = "03:04:05 PM" // Zero-padded so the width doesn't change during the day
+)
+
+// Option configures a Clock created by New
+type Option func(*Clock)
+
+// With12Hour makes the clock format times on the 12-hour clock with AM/PM
+func With12Hour() Option {
+	return func(c *Clock) {
+		c.Use12Hour = true
+	}
+}
+
+// WithWorkingHours sets the clock's working hours, given as time since local
+// midnight
+func WithWorkingHours(start, end time.Duration) Option {
+	return func(c *Clock) {
+		c.WorkStart = start
+		c.WorkEnd = end
+	}
+}
+
+// WithoutWeekday leaves the weekday out of the clock's date line
+func WithoutWeekday() Option {
+	return func(c *Clock) {
+		c.HideWeekday = true
+	}
+}
+
+// WithAbbrevOnly shows the zone abbreviation (e.g. "CET") on the date line
+// instead of the UTC offset, keeping the offset for zones without one
+func WithAbbrevOnly() Option {
+	return func(c *Clock) {
+		c.AbbrevOnly = true
+	}
+}
+
+// WithDSTWarning makes NextDSTTransition and FormatDSTWarning look for UTC
+// offset changes within window from now
+func WithDSTWarning(window time.Duration) Option {
+	return func(c *Clock) {
+		c.DSTWindow = window
+	}
+}
+
+// WithFormat makes the clock format times with a custom layout in the syntax
+// of time.Format, e.g. "15:04" or "3:04 PM". An empty layout keeps the default
+func WithFormat(layout string) Option {
+	return func(c *Clock) {
+		c.Format = layout
+	}
 }
 
 // New creates a new Clock instance
-func New(name, timezone string) (*Clock, error) {
+func New(name, timezone string, opts ...Option) (*Clock, error) {
 	loc, err := time.LoadLocation(timezone)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load timezone '%s': %w", timezone, err)
 	}
 
-	return &Clock{
-		Name:     name,
-		Location: loc,
-	}, nil
+	c := &Clock{
+		Name:      name,
+		Location:  loc,
+		WorkStart: DefaultWorkStart,
+		WorkEnd:   DefaultWorkEnd,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
 }
 
-// GetTime returns the current time in the clock's timezone
+// NewMulti creates a Clock showing several labelled zones on one card
+// The first zone's timezone is used as the clock's own location
+func NewMulti(name string, zones []*Clock, opts ...Option) (*Clock, error) {
+	if len(zones) == 0 {
+		return nil, fmt.Errorf("clock '%s' has no zones", name)
+	}
+
+	c := &Clock{
+		Name:      name,
+		Location:  zones[0].Location,
+		Zones:     zones,
+		WorkStart: DefaultWorkStart,
+		WorkEnd:   DefaultWorkEnd,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Now returns the current time for every clock, replaceable so formatting
+// and sorting can be checked against fixed instants such as DST boundaries
+var Now = time.Now
+
+// GetTime returns the current time in the clock's timezone, or the frozen
+// instant while the clock is frozen
 func (c *Clock) GetTime() time.Time {
-	return time.Now().In(c.Location)
+	return c.timeAt(Now())
+}
+
+// timeAt returns now in the clock's timezone, or the frozen instant while the
+// clock is frozen, so several clocks can be read at one captured instant
+func (c *Clock) timeAt(now time.Time) time.Time {
+	if !c.Frozen.IsZero() {
+		return c.Frozen.In(c.Location)
+	}
+	return now.In(c.Location)
 }
 
-// FormatTime returns the time in 24-hour format (HH:MM:SS)
+// At returns a copy of the clock, and of its zones, that reads the instant
+// now instead of the current time, so every line of a card shows one instant
+// A frozen clock keeps its frozen instant
+func (c *Clock) At(now time.Time) *Clock {
+	at := *c
+	if at.Frozen.IsZero() {
+		at.Frozen = now
+	}
+	if len(c.Zones) > 0 {
+		at.Zones = make([]*Clock, len(c.Zones))
+		for i, zone := range c.Zones {
+			at.Zones[i] = zone.At(now)
+		}
+	}
+	return &at
+}
+
+// Freeze makes clocks and their zones show the instant t instead of the
+// current time, or run again when t is zero
+func Freeze(clocks []*Clock, t time.Time) {
+	for _, c := range clocks {
+		c.Frozen = t
+		Freeze(c.Zones, t)
+	}
+}
+
+// FormatTime returns the time in 24-hour format (HH:MM:SS), in 12-hour
+// format (HH:MM:SS AM/PM) if the clock uses the 12-hour clock, or in the
+// clock's custom format if set
 func (c *Clock) FormatTime() string {
-	return c.GetTime().Format("15:04:05")
+	return c.GetTime().Format(c.timeLayout())
+}
+
+// FormatTime12 returns the time in 12-hour format (HH:MM:SS AM/PM)
+// Midnight is 12:00:00 AM and noon is 12:00:00 PM
+func (c *Clock) FormatTime12() string {
+	return c.GetTime().Format(layout12Hour)
+}
+
+// FormatTimeIn returns the current time in loc, formatted like FormatTime
+func (c *Clock) FormatTimeIn(loc *time.Location) string {
+	return c.GetTime().In(loc).Format(c.timeLayout())
+}
+
+// FormatTimeShort returns the time like FormatTime, without the seconds
+func (c *Clock) FormatTimeShort() string {
+	return c.GetTime().Format(withoutSeconds(c.timeLayout()))
+}
+
+// FormatTimeShortIn returns the current time in loc, formatted like
+// FormatTimeShort
+func (c *Clock) FormatTimeShortIn(loc *time.Location) string {
+	return c.GetTime().In(loc).Format(withoutSeconds(c.timeLayout()))
+}
+
+// FormatTimeAt returns t in the clock's timezone, formatted like
+// FormatTimeShort
+func (c *Clock) FormatTimeAt(t time.Time) string {
+	return t.In(c.Location).Format(withoutSeconds(c.timeLayout()))
 }
 
-// FormatDate returns the date in YYYY-MM-DD format
+// TimeAt returns the instant t, e.g. a time entered in another timezone, as
+// it reads in the clock's timezone
+func (c *Clock) TimeAt(t time.Time) time.Time {
+	return t.In(c.Location)
+}
+
+// withoutSeconds removes the seconds field from a time layout
+func withoutSeconds(layout string) string {
+	return strings.Replace(layout, ":05", "", 1)
+}
+
+// timeLayout returns the layout used to format times
+func (c *Clock) timeLayout() string {
+	if c.Format != "" {
+		return c.Format
+	}
+	if c.Use12Hour {
+		return layout12Hour
+	}
+	return layout24Hour
+}
+
+// FormatDate returns the date in the clock's calendar, YYYY-MM-DD by default
 func (c *Clock) FormatDate() string {
-	return c.GetTime().Format("2006-01-02")
+	if c.Calendar != nil {
+		return c.Calendar(c.GetTime())
+	}
+	return Gregorian(c.GetTime())
+}
+
+// ISODate returns the ISO 8601 week date, e.g. 2025-W49-3
+func (c *Clock) ISODate() string {
+	return ISOWeekDate(c.GetTime())
 }
 
 // FormatUTCOffset returns the UTC offset in ±HH:MM format
 func (c *Clock) FormatUTCOffset() string {
-	t := c.GetTime()
-	_, offset := t.Zone()
+	_, offset := c.GetTime().Zone()
+	return formatOffset(offset)
+}
 
+// formatOffset formats an offset in seconds as UTC±HH:MM
+func formatOffset(offset int) string {
 	sign := "+"
 	if offset < 0 {
 		sign = "-"
@@ -57,22 +265,258 @@ func (c *Clock) FormatUTCOffset() string {
 	return fmt.Sprintf("UTC%s%02d:%02d", sign, hours, minutes)
 }
 
-// FormatDateWithOffset returns the date and UTC offset
-// Format: "YYYY-MM-DD - UTC±HH:MM"
+// FormatShortOffset formats an offset in seconds as UTC±H, adding minutes
+// only for zones that aren't a whole number of hours (e.g. UTC+5:45)
+func FormatShortOffset(offset int) string {
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+
+	hours := offset / 3600
+	minutes := (offset % 3600) / 60
+	if minutes != 0 {
+		return fmt.Sprintf("UTC%s%d:%02d", sign, hours, minutes)
+	}
+	return fmt.Sprintf("UTC%s%d", sign, hours)
+}
+
+// ZoneName returns the zone abbreviation from the current time's Zone, e.g.
+// "CET" or "EST". Go reports a numeric abbreviation like "+07" for zones that
+// have no name (e.g. Asia/Ho_Chi_Minh), for which ZoneName returns "", as it
+// does for UTC itself, so callers fall back to the numeric UTC offset
+func (c *Clock) ZoneName() string {
+	abbrev, _ := c.GetTime().Zone()
+	if abbrev == "" || abbrev == "UTC" || strings.ContainsAny(abbrev[:1], "+-0123456789") {
+		return ""
+	}
+	return abbrev
+}
+
+// FormatWeekday returns the abbreviated weekday, e.g. "Wed"
+func (c *Clock) FormatWeekday() string {
+	return c.GetTime().Format("Mon")
+}
+
+// FormatOffsetLabel returns the UTC offset followed by the zone abbreviation
+// if it has a name, e.g. "UTC+01:00 CET", or the abbreviation alone if the
+// clock shows only abbreviations
+func (c *Clock) FormatOffsetLabel() string {
+	offset := c.FormatUTCOffset()
+	if abbrev := c.ZoneName(); abbrev != "" {
+		if c.AbbrevOnly {
+			return abbrev
+		}
+		offset += " " + abbrev
+	}
+	return offset
+}
+
+// FormatDateWithOffset returns the weekday, date, and UTC offset
+// Format: "Wed YYYY-MM-DD - UTC±HH:MM CET", with the date in the clock's
+// calendar, the offset as FormatOffsetLabel shows it, and without the weekday
+// if the clock hides it
 func (c *Clock) FormatDateWithOffset() string {
-	return fmt.Sprintf("%s - %s", c.FormatDate(), c.FormatUTCOffset())
+	offset := c.FormatOffsetLabel()
+	if c.HideWeekday {
+		return fmt.Sprintf("%s - %s", c.FormatDate(), offset)
+	}
+	return fmt.Sprintf("%s %s - %s", c.FormatWeekday(), c.FormatDate(), offset)
 }
 
-// GetUTCOffset returns the UTC offset in seconds
-func (c *Clock) GetUTCOffset() int {
+// Snapshot is the formatted state of a clock at a single instant
+type Snapshot struct {
+	Name             string `json:"name"`
+	Timezone         string `json:"timezone"`
+	Time             string `json:"time"` // In the clock's time format, like FormatTime
+	Date             string `json:"date"`
+	UTCOffset        string `json:"utc_offset"`
+	UTCOffsetSeconds int    `json:"utc_offset_seconds"` // Same offset, so consumers don't have to parse it
+}
+
+// Snapshot returns the clock's time, date, and UTC offset, all taken from
+// the same instant
+func (c *Clock) Snapshot() Snapshot {
 	t := c.GetTime()
 	_, offset := t.Zone()
+	return Snapshot{
+		Name:             c.Name,
+		Timezone:         c.Location.String(),
+		Time:             c.FormatTime(),
+		Date:             Gregorian(t),
+		UTCOffset:        formatOffset(offset),
+		UTCOffsetSeconds: offset,
+	}
+}
+
+// FormatUnix returns the current Unix timestamp in seconds
+// The value is the same for every timezone
+func (c *Clock) FormatUnix() string {
+	return strconv.FormatInt(c.GetTime().Unix(), 10)
+}
+
+// InWorkingHours reports whether the clock's local time of day is within
+// [WorkStart, WorkEnd) on a weekday. The time is read in the clock's own
+// timezone, so zones with fractional offsets (e.g. UTC+05:45) switch at their
+// local boundary
+func (c *Clock) InWorkingHours() bool {
+	return c.WorkingAt(c.GetTime())
+}
+
+// WorkingAt reports whether t falls within the clock's working hours, like
+// InWorkingHours does for the current time
+func (c *Clock) WorkingAt(t time.Time) bool {
+	t = t.In(c.Location)
+	if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		return false
+	}
+	sinceMidnight := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	return sinceMidnight >= c.WorkStart && sinceMidnight < c.WorkEnd
+}
+
+// OffsetFrom returns how far the clock's UTC offset is ahead of other's
+func (c *Clock) OffsetFrom(other *Clock) time.Duration {
+	return time.Duration(c.GetUTCOffset()-other.GetUTCOffset()) * time.Second
+}
+
+// FormatRelativeOffset returns the offset from home as "+3h", "-5h30m", or
+// "same" if both clocks have the same UTC offset
+func (c *Clock) FormatRelativeOffset(home *Clock) string {
+	d := c.OffsetFrom(home)
+	if d == 0 {
+		return "same"
+	}
+	return formatOffsetChange(d)
+}
+
+// formatOffsetChange formats a difference between UTC offsets as "+3h",
+// "-5h30m", or "+30m"
+func formatOffsetChange(d time.Duration) string {
+	sign := "+"
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+	hours := int(d.Hours())
+	minutes := int(d.Minutes()) % 60
+	switch {
+	case minutes == 0:
+		return fmt.Sprintf("%s%dh", sign, hours)
+	case hours == 0:
+		return fmt.Sprintf("%s%dm", sign, minutes)
+	}
+	return fmt.Sprintf("%s%dh%02dm", sign, hours, minutes)
+}
+
+// IsWeekend reports whether it's Saturday or Sunday in the clock's timezone
+func (c *Clock) IsWeekend() bool {
+	day := c.GetTime().Weekday()
+	return day == time.Saturday || day == time.Sunday
+}
+
+// GetUTCOffset returns the UTC offset in seconds
+func (c *Clock) GetUTCOffset() int {
+	return c.utcOffsetAt(Now())
+}
+
+// utcOffsetAt returns the UTC offset in seconds at the instant now, like
+// GetUTCOffset
+func (c *Clock) utcOffsetAt(now time.Time) int {
+	_, offset := c.timeAt(now).Zone()
 	return offset
 }
 
+// utcOffsets returns the UTC offset of each clock, all taken at the instant
+// now so clocks on either side of a DST change compare consistently
+func utcOffsets(clocks []*Clock, now time.Time) map[*Clock]int {
+	offsets := make(map[*Clock]int, len(clocks))
+	for _, c := range clocks {
+		offsets[c] = c.utcOffsetAt(now)
+	}
+	return offsets
+}
+
+// OffsetSpan returns the smallest and largest UTC offset in seconds among
+// clocks, including every zone of multi-timezone clocks
+func OffsetSpan(clocks []*Clock) (minOffset, maxOffset int) {
+	now := Now()
+	first := true
+	for _, c := range clocks {
+		zones := c.Zones
+		if len(zones) == 0 {
+			zones = []*Clock{c}
+		}
+		for _, z := range zones {
+			offset := z.utcOffsetAt(now)
+			if first || offset < minOffset {
+				minOffset = offset
+			}
+			if first || offset > maxOffset {
+				maxOffset = offset
+			}
+			first = false
+		}
+	}
+	return minOffset, maxOffset
+}
+
 // SortByUTCOffset sorts a slice of clocks by their UTC offset (west to east)
 func SortByUTCOffset(clocks []*Clock) {
+	SortByUTCOffsetAt(clocks, Now(), false)
+}
+
+// SortByUTCOffsetDesc sorts a slice of clocks by their UTC offset (east to west)
+func SortByUTCOffsetDesc(clocks []*Clock) {
+	SortByUTCOffsetAt(clocks, Now(), true)
+}
+
+// SortByUTCOffsetAt sorts clocks by their UTC offsets at the instant now
+// (west to east, or east to west if desc), for callers that render the clocks
+// at the same instant
+func SortByUTCOffsetAt(clocks []*Clock, now time.Time, desc bool) {
+	offsets := utcOffsets(clocks, now)
 	sort.Slice(clocks, func(i, j int) bool {
-		return clocks[i].GetUTCOffset() < clocks[j].GetUTCOffset()
+		if desc {
+			return offsets[clocks[i]] > offsets[clocks[j]]
+		}
+		return offsets[clocks[i]] < offsets[clocks[j]]
+	})
+}
+
+// SortByWorkingHours sorts clocks within working hours ahead of the others,
+// each group by UTC offset (west to east, or east to west if desc)
+func SortByWorkingHours(clocks []*Clock, desc bool) {
+	SortByWorkingHoursAt(clocks, Now(), desc)
+}
+
+// SortByWorkingHoursAt sorts clocks like SortByWorkingHours, with working
+// hours and offsets taken at the instant now
+func SortByWorkingHoursAt(clocks []*Clock, now time.Time, desc bool) {
+	offsets := utcOffsets(clocks, now)
+	working := make(map[*Clock]bool, len(clocks))
+	for _, c := range clocks {
+		working[c] = c.WorkingAt(c.timeAt(now))
+	}
+	sort.SliceStable(clocks, func(i, j int) bool {
+		if working[clocks[i]] != working[clocks[j]] {
+			return working[clocks[i]]
+		}
+		if desc {
+			return offsets[clocks[i]] > offsets[clocks[j]]
+		}
+		return offsets[clocks[i]] < offsets[clocks[j]]
+	})
+}
+
+// SortByName sorts clocks alphabetically by name, ignoring case
+// (Z to A if desc)
+func SortByName(clocks []*Clock, desc bool) {
+	sort.SliceStable(clocks, func(i, j int) bool {
+		a, b := strings.ToLower(clocks[i].Name), strings.ToLower(clocks[j].Name)
+		if desc {
+			return a > b
+		}
+		return a < b
 	})
 }
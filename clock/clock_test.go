@@ -0,0 +1,318 @@
+package clock
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setNow makes the package read the given instant as the current time until
+// the test ends
+func setNow(t *testing.T, now time.Time) {
+	t.Helper()
+	original := Now
+	Now = func() time.Time { return now }
+	t.Cleanup(func() { Now = original })
+}
+
+func TestInWorkingHoursKathmandu(t *testing.T) {
+	// Kathmandu is UTC+05:45, so its working hours start and end at a
+	// quarter past the hour in UTC
+	c, err := New("Kathmandu", "Asia/Kathmandu")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want bool
+	}{
+		{"08:59 local", time.Date(2024, 3, 13, 3, 14, 0, 0, time.UTC), false},
+		{"09:00 local", time.Date(2024, 3, 13, 3, 15, 0, 0, time.UTC), true},
+		{"16:59 local", time.Date(2024, 3, 13, 11, 14, 0, 0, time.UTC), true},
+		{"17:00 local", time.Date(2024, 3, 13, 11, 15, 0, 0, time.UTC), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setNow(t, tt.now)
+			if local := c.GetTime().Format("15:04"); local != tt.name[:5] {
+				t.Fatalf("GetTime() = %s, want %s", local, tt.name[:5])
+			}
+			if got := c.InWorkingHours(); got != tt.want {
+				t.Errorf("InWorkingHours() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// newClocks creates clocks named after their timezones' cities
+func newClocks(t *testing.T, timezones ...string) []*Clock {
+	t.Helper()
+	var clocks []*Clock
+	for _, tz := range timezones {
+		c, err := New(tz[strings.LastIndex(tz, "/")+1:], tz)
+		if err != nil {
+			t.Fatalf("New(%q) error = %v", tz, err)
+		}
+		clocks = append(clocks, c)
+	}
+	return clocks
+}
+
+// clockNames returns the names of clocks in order
+func clockNames(clocks []*Clock) []string {
+	var names []string
+	for _, c := range clocks {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+func TestSortByUTCOffsetAcrossDSTChange(t *testing.T) {
+	// On 2024-03-15 New York has moved to daylight time (UTC-4) while
+	// London (UTC+0) and Berlin (UTC+1) haven't yet
+	setNow(t, time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC))
+	clocks := newClocks(t, "Europe/Berlin", "Europe/London", "America/New_York")
+
+	SortByUTCOffset(clocks)
+	want := []string{"New_York", "London", "Berlin"}
+	if got := clockNames(clocks); !slices.Equal(got, want) {
+		t.Fatalf("SortByUTCOffset() order = %v, want %v", got, want)
+	}
+	wantOffsets := []string{"UTC-04:00", "UTC+00:00", "UTC+01:00"}
+	for i, c := range clocks {
+		if got := c.FormatUTCOffset(); got != wantOffsets[i] {
+			t.Errorf("%s FormatUTCOffset() = %s, want %s", c.Name, got, wantOffsets[i])
+		}
+	}
+}
+
+func TestSortByUTCOffsetAtOneInstant(t *testing.T) {
+	// Every read of the time moves an hour on, across London's change to
+	// UTC+1 at 01:00 UTC on 2024-03-31. Sorting must read it only once
+	before := time.Date(2024, 3, 31, 0, 59, 0, 0, time.UTC)
+	reads := 0
+	original := Now
+	Now = func() time.Time {
+		reads++
+		return before.Add(time.Duration(reads-1) * time.Hour)
+	}
+	t.Cleanup(func() { Now = original })
+
+	clocks := newClocks(t, "Asia/Dubai", "Europe/London", "America/Sao_Paulo")
+	SortByUTCOffset(clocks)
+	want := []string{"Sao_Paulo", "London", "Dubai"}
+	if got := clockNames(clocks); !slices.Equal(got, want) {
+		t.Errorf("SortByUTCOffset() order = %v, want %v", got, want)
+	}
+	if reads != 1 {
+		t.Errorf("SortByUTCOffset() read the time %d times, want 1", reads)
+	}
+}
+
+func TestAtReadsOneInstant(t *testing.T) {
+	setNow(t, time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC))
+	multi, err := NewMulti("Office", newClocks(t, "Europe/London", "Asia/Tokyo"))
+	if err != nil {
+		t.Fatalf("NewMulti() error = %v", err)
+	}
+	at := time.Date(2024, 6, 1, 8, 30, 0, 0, time.UTC)
+
+	copied := multi.At(at)
+	if got := copied.Zones[0].FormatTime(); got != "09:30:00" {
+		t.Errorf("London zone FormatTime() = %s, want 09:30:00", got)
+	}
+	if got := copied.Zones[1].FormatTime(); got != "17:30:00" {
+		t.Errorf("Tokyo zone FormatTime() = %s, want 17:30:00", got)
+	}
+	// The original keeps reading the current time
+	if got := multi.Zones[0].FormatTime(); got != "12:00:00" {
+		t.Errorf("original London zone FormatTime() = %s, want 12:00:00", got)
+	}
+
+	// Frozen clocks keep their frozen instant
+	frozen := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	Freeze([]*Clock{multi}, frozen)
+	if got := multi.At(at).Zones[1].FormatTime(); got != "09:00:00" {
+		t.Errorf("frozen Tokyo zone FormatTime() = %s, want 09:00:00", got)
+	}
+}
+
+func TestFormattingAtFixedInstants(t *testing.T) {
+	tests := []struct {
+		name     string
+		timezone string
+		now      time.Time
+		opts     []Option
+		time     string
+		date     string
+		offset   int
+	}{
+		{"winter", "Europe/Berlin", time.Date(2024, 1, 15, 11, 5, 9, 0, time.UTC), nil, "12:05:09", "2024-01-15", 3600},
+		{"summer", "Europe/Berlin", time.Date(2024, 7, 15, 11, 5, 9, 0, time.UTC), nil, "13:05:09", "2024-07-15", 7200},
+		{"12-hour", "America/New_York", time.Date(2024, 7, 15, 17, 5, 9, 0, time.UTC), []Option{With12Hour()}, "01:05:09 PM", "2024-07-15", -4 * 3600},
+		{"date behind UTC", "America/Los_Angeles", time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC), nil, "19:00:00", "2023-12-31", -8 * 3600},
+		{"quarter-hour offset", "Asia/Kathmandu", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), nil, "05:45:00", "2024-01-01", 5*3600 + 45*60},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setNow(t, tt.now)
+			c, err := New(tt.name, tt.timezone, tt.opts...)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if got := c.FormatTime(); got != tt.time {
+				t.Errorf("FormatTime() = %s, want %s", got, tt.time)
+			}
+			if got := c.FormatDate(); got != tt.date {
+				t.Errorf("FormatDate() = %s, want %s", got, tt.date)
+			}
+			if got := c.GetUTCOffset(); got != tt.offset {
+				t.Errorf("GetUTCOffset() = %d, want %d", got, tt.offset)
+			}
+		})
+	}
+}
+
+func TestSortByUTCOffsetFollowsNow(t *testing.T) {
+	// Adelaide is UTC+10:30 in its summer and UTC+9:30 in its winter, so it
+	// changes places with Brisbane, which stays at UTC+10
+	clocks := newClocks(t, "Australia/Adelaide", "Australia/Brisbane", "Europe/London")
+	tests := []struct {
+		now  time.Time
+		want []string
+	}{
+		{time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC), []string{"London", "Brisbane", "Adelaide"}},
+		{time.Date(2024, 7, 15, 12, 0, 0, 0, time.UTC), []string{"London", "Adelaide", "Brisbane"}},
+	}
+	for _, tt := range tests {
+		setNow(t, tt.now)
+		SortByUTCOffset(clocks)
+		if got := clockNames(clocks); !slices.Equal(got, tt.want) {
+			t.Errorf("SortByUTCOffset() at %s = %v, want %v", tt.now.Format(time.DateOnly), got, tt.want)
+		}
+		SortByUTCOffsetDesc(clocks)
+		want := slices.Clone(tt.want)
+		slices.Reverse(want)
+		if got := clockNames(clocks); !slices.Equal(got, want) {
+			t.Errorf("SortByUTCOffsetDesc() at %s = %v, want %v", tt.now.Format(time.DateOnly), got, want)
+		}
+	}
+}
+
+func TestDSTWarning(t *testing.T) {
+	tests := []struct {
+		name     string
+		timezone string
+		now      time.Time
+		want     string
+	}{
+		// Clocks go back at 03:00 local on 2024-04-07 in Sydney
+		{"southern hemisphere", "Australia/Sydney", time.Date(2024, 4, 5, 12, 0, 0, 0, time.UTC), "DST -1h in 1d"},
+		{"northern hemisphere", "Europe/Berlin", time.Date(2024, 3, 30, 20, 0, 0, 0, time.UTC), "DST +1h in 5h"},
+		// Lord Howe Island only moves its clocks by half an hour
+		{"half hour", "Australia/Lord_Howe", time.Date(2024, 4, 5, 12, 0, 0, 0, time.UTC), "DST -30m in 1d"},
+		{"no DST", "Asia/Tokyo", time.Date(2024, 3, 30, 20, 0, 0, 0, time.UTC), ""},
+		{"outside the window", "Europe/Berlin", time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setNow(t, tt.now)
+			c, err := New(tt.name, tt.timezone, WithDSTWarning(7*24*time.Hour))
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if got := c.FormatDSTWarning(); got != tt.want {
+				t.Errorf("FormatDSTWarning() = %q, want %q", got, tt.want)
+			}
+			if _, ok := c.NextDSTTransition(); ok != (tt.want != "") {
+				t.Errorf("NextDSTTransition() found a transition = %v, want %v", ok, tt.want != "")
+			}
+		})
+	}
+}
+
+func TestFormatRelativeOffset(t *testing.T) {
+	setNow(t, time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	clocks := newClocks(t, "Europe/London", "America/New_York", "Asia/Kolkata", "Australia/Adelaide", "Australia/Brisbane", "Europe/Dublin")
+	home := clocks[0]
+	want := []string{"same", "-5h", "+5h30m", "+10h30m", "+10h", "same"}
+	for i, c := range clocks {
+		if got := c.FormatRelativeOffset(home); got != want[i] {
+			t.Errorf("%s FormatRelativeOffset() = %q, want %q", c.Name, got, want[i])
+		}
+	}
+	// Brisbane is half an hour behind Adelaide in the southern summer
+	if got := clocks[4].FormatRelativeOffset(clocks[3]); got != "-30m" {
+		t.Errorf("Brisbane FormatRelativeOffset(Adelaide) = %q, want %q", got, "-30m")
+	}
+}
+
+func TestZoneName(t *testing.T) {
+	setNow(t, time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	tests := []struct {
+		timezone string
+		name     string
+		date     string // FormatDateWithOffset with WithAbbrevOnly
+	}{
+		{"America/Los_Angeles", "PST", "Mon 2024-01-15 - PST"},
+		{"Europe/Berlin", "CET", "Mon 2024-01-15 - CET"},
+		// Go names these zones by their offset, which falls back to the
+		// numeric offset display
+		{"Asia/Ho_Chi_Minh", "", "Mon 2024-01-15 - UTC+07:00"},
+		{"Asia/Kathmandu", "", "Mon 2024-01-15 - UTC+05:45"},
+		{"UTC", "", "Mon 2024-01-15 - UTC+00:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.timezone, func(t *testing.T) {
+			c, err := New(tt.timezone, tt.timezone, WithAbbrevOnly())
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if got := c.ZoneName(); got != tt.name {
+				t.Errorf("ZoneName() = %q, want %q", got, tt.name)
+			}
+			if got := c.FormatDateWithOffset(); got != tt.date {
+				t.Errorf("FormatDateWithOffset() = %q, want %q", got, tt.date)
+			}
+		})
+	}
+}
+
+func TestSunPhase(t *testing.T) {
+	berlin := &Coordinates{Latitude: 52.52437, Longitude: 13.41053}
+	tests := []struct {
+		name        string
+		coordinates *Coordinates
+		now         time.Time
+		want        SunPhase
+	}{
+		// Sunrise in Berlin is at about 02:45 UTC on 2024-06-21
+		{"before dawn", berlin, time.Date(2024, 6, 21, 1, 0, 0, 0, time.UTC), SunNight},
+		{"dawn", berlin, time.Date(2024, 6, 21, 2, 30, 0, 0, time.UTC), SunTwilight},
+		{"after sunrise", berlin, time.Date(2024, 6, 21, 3, 0, 0, 0, time.UTC), SunDay},
+		{"noon", berlin, time.Date(2024, 6, 21, 11, 0, 0, 0, time.UTC), SunDay},
+		// Without coordinates the local hour counts, 06:00 to 18:00 as day
+		{"hour before day", nil, time.Date(2024, 6, 21, 3, 30, 0, 0, time.UTC), SunTwilight},
+		{"hour of day", nil, time.Date(2024, 6, 21, 4, 0, 0, 0, time.UTC), SunDay},
+		{"hour of night", nil, time.Date(2024, 6, 21, 20, 0, 0, 0, time.UTC), SunNight},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setNow(t, tt.now)
+			c, err := New("Berlin", "Europe/Berlin")
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			c.Coordinates = tt.coordinates
+			if got := c.SunPhase(); got != tt.want {
+				t.Errorf("SunPhase() = %v, want %v", got, tt.want)
+			}
+			if got := c.IsDaytime(); got != (tt.want == SunDay) {
+				t.Errorf("IsDaytime() = %v, want %v", got, tt.want == SunDay)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package clock
+
+import (
+	"fmt"
+	"time"
+)
+
+// NextDSTTransition returns when the clock's UTC offset next changes, if that
+// happens within the clock's DSTWindow. Offsets are compared rather than DST
+// flags, so zones in either hemisphere are handled alike and zones without
+// DST never report a transition
+func (c *Clock) NextDSTTransition() (time.Time, bool) {
+	start := c.GetTime()
+	_, offset := start.Zone()
+
+	// Probe hourly for a changed offset, then narrow down to the minute
+	before := start
+	for probe := start.Add(time.Hour); !probe.After(start.Add(c.DSTWindow)); probe = probe.Add(time.Hour) {
+		if _, o := probe.Zone(); o != offset {
+			after := probe
+			for after.Sub(before) > time.Minute {
+				mid := before.Add(after.Sub(before) / 2)
+				if _, o := mid.Zone(); o != offset {
+					after = mid
+				} else {
+					before = mid
+				}
+			}
+			return after.Truncate(time.Minute), true
+		}
+		before = probe
+	}
+	return time.Time{}, false
+}
+
+// FormatDSTWarning returns a short note on an offset change within the
+// clock's DSTWindow, e.g. "DST +1h in 2d" or "DST -30m in 5h", or "" if there
+// is none
+func (c *Clock) FormatDSTWarning() string {
+	transition, ok := c.NextDSTTransition()
+	if !ok {
+		return ""
+	}
+	_, before := c.GetTime().Zone()
+	_, after := transition.Zone()
+	shift := formatOffsetChange(time.Duration(after-before) * time.Second)
+
+	until := transition.Sub(c.GetTime())
+	when := fmt.Sprintf("%dm", int(until.Minutes()))
+	switch {
+	case until >= 24*time.Hour:
+		when = fmt.Sprintf("%dd", int(until.Hours()/24))
+	case until >= time.Hour:
+		when = fmt.Sprintf("%dh", int(until.Hours()))
+	}
+	return fmt.Sprintf("DST %s in %s", shift, when)
+}
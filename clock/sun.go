@@ -0,0 +1,98 @@
+package clock
+
+import (
+	"math"
+	"time"
+)
+
+// SunPhase describes the position of the sun at a clock's location
+type SunPhase int
+
+const (
+	SunNight    SunPhase = iota // Sun well below the horizon
+	SunTwilight                 // Dawn or dusk
+	SunDay                      // Sun above the horizon
+)
+
+// Coordinates is a geographic position in decimal degrees
+type Coordinates struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Solar elevations in degrees that separate the sun phases: sunrise and
+// sunset, allowing for refraction and the size of the sun's disc, and the end
+// of civil twilight
+const (
+	sunriseElevation  = -0.833
+	twilightElevation = -6.0
+)
+
+// Daytime band used for clocks without coordinates, as local hours. The hour
+// on either side counts as twilight
+const (
+	daytimeStart = 6
+	daytimeEnd   = 18
+)
+
+// SunPhase returns the current sun phase at the clock's location
+// Clocks with coordinates use the solar elevation: day from sunrise to
+// sunset, twilight until the sun is 6 degrees below the horizon. Clocks
+// without count 06:00 to 18:00 local time as day
+func (c *Clock) SunPhase() SunPhase {
+	t := c.GetTime()
+
+	if c.Coordinates == nil {
+		hour := t.Hour()
+		switch {
+		case hour >= daytimeStart && hour < daytimeEnd:
+			return SunDay
+		case hour >= daytimeStart-1 && hour < daytimeEnd+1:
+			return SunTwilight
+		}
+		return SunNight
+	}
+
+	elevation := SolarElevation(t, c.Coordinates.Latitude, c.Coordinates.Longitude)
+	switch {
+	case elevation > sunriseElevation:
+		return SunDay
+	case elevation > twilightElevation:
+		return SunTwilight
+	}
+	return SunNight
+}
+
+// IsDaytime reports whether the sun is up at the clock's location, by the
+// same rules as SunPhase
+func (c *Clock) IsDaytime() bool {
+	return c.SunPhase() == SunDay
+}
+
+// SolarElevation returns the sun's elevation above the horizon in degrees at
+// the given instant and position, using a low-precision solar position
+// algorithm (accurate to about a degree)
+func SolarElevation(t time.Time, latitude, longitude float64) float64 {
+	const rad = math.Pi / 180
+
+	// Days since J2000.0
+	n := float64(t.Unix())/86400.0 + 2440587.5 - 2451545.0
+
+	// Ecliptic longitude of the sun
+	meanLongitude := math.Mod(280.460+0.9856474*n, 360)
+	meanAnomaly := math.Mod(357.528+0.9856003*n, 360) * rad
+	eclipticLongitude := (meanLongitude + 1.915*math.Sin(meanAnomaly) + 0.020*math.Sin(2*meanAnomaly)) * rad
+	obliquity := (23.439 - 0.0000004*n) * rad
+
+	// Equatorial coordinates
+	rightAscension := math.Atan2(math.Cos(obliquity)*math.Sin(eclipticLongitude), math.Cos(eclipticLongitude))
+	declination := math.Asin(math.Sin(obliquity) * math.Sin(eclipticLongitude))
+
+	// Local sidereal time and hour angle
+	siderealHours := math.Mod(18.697374558+24.06570982441908*n, 24)
+	hourAngle := siderealHours*15*rad + longitude*rad - rightAscension
+
+	lat := latitude * rad
+	elevation := math.Asin(math.Sin(lat)*math.Sin(declination) + math.Cos(lat)*math.Cos(declination)*math.Cos(hourAngle))
+	return elevation / rad
+}
@@ -1,26 +1,152 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/philtim/worldclock/geonames"
 	"gopkg.in/yaml.v3"
 )
 
 // City represents a clock configuration for a city
 type City struct {
+	Name      string   `yaml:"name"`
+	Label     string   `yaml:"label,omitempty"` // Optional text shown instead of the name, which stays the identifier
+	Timezone  string   `yaml:"timezone,omitempty"`
+	Zones     []Zone   `yaml:"zones,omitempty"`      // Several labelled timezones on one card, instead of timezone
+	Icon      string   `yaml:"icon,omitempty"`       // Optional prefix shown before the title, e.g. an emoji
+	Color     string   `yaml:"color,omitempty"`      // Optional title and border color: an ANSI color 0-255 or hex like "#ff8800"
+	Short     string   `yaml:"short,omitempty"`      // Optional code for --compact, e.g. "NYC", instead of the first three letters
+	Format    string   `yaml:"format,omitempty"`     // Optional time layout, e.g. "15:04" or "3:04 PM"
+	Home      bool     `yaml:"home,omitempty"`       // Other clocks show their offset from this city
+	Latitude  *float64 `yaml:"latitude,omitempty"`   // Optional, used for sunrise/sunset tinting
+	Longitude *float64 `yaml:"longitude,omitempty"`  // Optional, used for sunrise/sunset tinting
+	WorkStart string   `yaml:"work_start,omitempty"` // Optional HH:MM, overrides the global work_start
+	WorkEnd   string   `yaml:"work_end,omitempty"`   // Optional HH:MM, overrides the global work_end
+}
+
+// DisplayName returns the city's label, or its name if it has none
+func (c City) DisplayName() string {
+	if c.Label != "" {
+		return c.Label
+	}
+	return c.Name
+}
+
+// Same reports whether c and other are the same clock entry. Cities are
+// identified by name, label and timezone, and by their coordinates when both
+// have them, so one city can be added again under another label
+func (c City) Same(other City) bool {
+	if c.Name != other.Name || c.Label != other.Label || c.Timezone != other.Timezone {
+		return false
+	}
+	if c.hasCoordinates() && other.hasCoordinates() {
+		return *c.Latitude == *other.Latitude && *c.Longitude == *other.Longitude
+	}
+	return true
+}
+
+// hasCoordinates reports whether the city's position is known
+func (c City) hasCoordinates() bool {
+	return c.Latitude != nil && c.Longitude != nil
+}
+
+// showsTimezone reports whether the city shows tz, as its timezone or as one
+// of its zones
+func (c City) showsTimezone(tz string) bool {
+	return c.Timezone == tz || slices.ContainsFunc(c.Zones, func(zone Zone) bool {
+		return zone.Timezone == tz
+	})
+}
+
+// Zone is one labelled timezone of a multi-timezone city
+type Zone struct {
 	Name     string `yaml:"name"`
 	Timezone string `yaml:"timezone"`
 }
 
 // Config represents the application configuration
 type Config struct {
-	Cities []City `yaml:"cities"`
+	Cities              []City `yaml:"cities"`
+	ShowTimezone        bool   `yaml:"show_timezone,omitempty"`         // Show IANA timezone instead of city name on cards
+	ASCII               bool   `yaml:"ascii,omitempty"`                 // Use ASCII-only borders and glyphs
+	ShowUnix            bool   `yaml:"show_unix,omitempty"`             // Show the Unix timestamp above the clocks
+	ShowLocalEquivalent bool   `yaml:"show_local_equivalent,omitempty"` // Show the local time below each clock
+	ShowLocalOffset     bool   `yaml:"show_local_offset,omitempty"`     // Show each clock's offset from the local timezone
+	ShowWorkingHours    bool   `yaml:"show_working_hours,omitempty"`    // Mark clocks that are within working hours
+	BusinessHoursColors bool   `yaml:"business_hours_colors,omitempty"` // Color times green within working hours and red outside
+	MaxClocks           int    `yaml:"max_clocks,omitempty"`            // Maximum number of cities, 0 for unlimited
+	Columns             int    `yaml:"columns,omitempty"`               // Clocks per row, 0 to fit as many as the width allows
+	Layout              string `yaml:"layout,omitempty"`                // Clock layout: grid of cards (default) or list of single lines
+	SunTint             bool   `yaml:"sun_tint,omitempty"`              // Tint cards by day, twilight, and night
+	DayNightBorder      bool   `yaml:"day_night_border,omitempty"`      // Color card borders warm by day and cool by night
+	ShowDayNight        bool   `yaml:"show_day_night,omitempty"`        // Show a sun or moon next to each city name
+	AbbreviateNames     bool   `yaml:"abbreviate_names,omitempty"`      // Shorten names that don't fit on a card
+	ReportFocus         *bool  `yaml:"report_focus,omitempty"`          // Refresh when the terminal regains focus (default true)
+	SortDesc            bool   `yaml:"sort_desc,omitempty"`             // Sort clocks east to west instead of west to east
+	StaleDataDays       int    `yaml:"stale_data_days,omitempty"`       // Warn when city data is older than this, 0 for 180 days, negative to disable
+	DSTWarningDays      int    `yaml:"dst_warning_days,omitempty"`      // Warn on cards whose offset changes within this many days, 0 for 7, negative to disable
+	ShowOffsetSpan      bool   `yaml:"show_offset_span,omitempty"`      // Show the range of UTC offsets above the clocks
+	Calendar            string `yaml:"calendar,omitempty"`              // Date line calendar: gregorian (default) or iso-week
+	Sort                string `yaml:"sort,omitempty"`                  // Sort mode: offset (default) or active_first
+	TimeFormat          string `yaml:"time_format,omitempty"`           // 24h (default) or 12h, cities with a format override it
+	HideSeconds         bool   `yaml:"hide_seconds,omitempty"`          // Show times without seconds
+	HideWeekday         bool   `yaml:"hide_weekday,omitempty"`          // Leave the weekday out of the date line
+	ShowAbbrev          bool   `yaml:"show_abbrev,omitempty"`           // Show zone abbreviations like CET instead of the UTC offset where the zone has one
+	HighlightWeekends   *bool  `yaml:"highlight_weekends,omitempty"`    // Mute the border of clocks on a weekend (default true)
+	WorkStart           string `yaml:"work_start,omitempty"`            // Start of working hours as HH:MM (default 09:00)
+	WorkEnd             string `yaml:"work_end,omitempty"`              // End of working hours as HH:MM (default 17:00)
+	Dataset             string `yaml:"dataset,omitempty"`               // GeoNames dataset to search: cities15000 (default), cities5000, cities1000, or cities500, overridden by WORLDCLOCK_GEONAMES_DATASET
+	GeoNamesURL         string `yaml:"geonames_url,omitempty"`          // Mirror to download GeoNames data from, overridden by WORLDCLOCK_GEONAMES_URL
+	Theme               string `yaml:"theme,omitempty"`                 // Color palette: dark (default), light, or auto to follow $COLORFGBG
 }
 
-// Load reads the configuration from ~/.config/worldclock.yaml
+// Time formats
+const (
+	TimeFormat24h = "24h"
+	TimeFormat12h = "12h"
+)
+
+// Layouts
+const (
+	LayoutGrid = "grid"
+	LayoutList = "list"
+)
+
+// Themes
+const (
+	ThemeDark  = "dark"
+	ThemeLight = "light"
+	ThemeAuto  = "auto"
+)
+
+// Calendars, the names of the clock package's calendars
+const (
+	CalendarGregorian = "gregorian"
+	CalendarISOWeek   = "iso-week"
+)
+
+// Sort modes
+const (
+	SortOffset      = "offset"       // By UTC offset
+	SortActiveFirst = "active_first" // Clocks within working hours first, then by UTC offset
+	SortManual      = "manual"       // In the order of the config file
+	SortName        = "name"         // Alphabetically by the name shown on the card
+)
+
+// defaultStaleDataDays is the city data age that triggers a warning by default
+const defaultStaleDataDays = 180
+
+// defaultDSTWarningDays is how far ahead cards warn of DST changes by default
+const defaultDSTWarningDays = 7
+
+// Load reads the configuration from ~/.config/worldclock.yaml or $WORLDCLOCK_CONFIG
 // If the file doesn't exist, returns an empty config
 func Load() (*Config, error) {
 	configPath, err := getConfigPath()
@@ -46,6 +172,11 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
 
+	// An empty file or "cities:" without entries is a valid empty config
+	if cfg.Cities == nil {
+		cfg.Cities = []City{}
+	}
+
 	// Validate timezones
 	if err := cfg.Validate(); err != nil {
 		return nil, err
@@ -54,27 +185,255 @@ func Load() (*Config, error) {
 	return &cfg, nil
 }
 
+// Environment variables that override the GeoNames settings
+const (
+	GeoNamesDatasetEnv = "WORLDCLOCK_GEONAMES_DATASET"
+	GeoNamesURLEnv     = "WORLDCLOCK_GEONAMES_URL"
+)
+
+// GeoNamesDataset returns the GeoNames dataset to search, taken from the
+// environment, the config, or the default in that order
+func (c *Config) GeoNamesDataset() (geonames.Dataset, error) {
+	if env := os.Getenv(GeoNamesDatasetEnv); env != "" {
+		d, err := geonames.ParseDataset(env)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", GeoNamesDatasetEnv, err)
+		}
+		return d, nil
+	}
+	return geonames.ParseDataset(c.Dataset)
+}
+
+// GeoNamesBaseURL returns the URL GeoNames data is downloaded from, taken from
+// the environment, the config, or the default in that order
+func (c *Config) GeoNamesBaseURL() (string, error) {
+	if env := os.Getenv(GeoNamesURLEnv); env != "" {
+		u, err := geonames.ParseBaseURL(env)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", GeoNamesURLEnv, err)
+		}
+		return u, nil
+	}
+	return geonames.ParseBaseURL(c.GeoNamesURL)
+}
+
 // Validate checks that all timezone identifiers are valid
 func (c *Config) Validate() error {
+	if c.MaxClocks < 0 {
+		return fmt.Errorf("max_clocks must not be negative")
+	}
+	if c.Columns < 0 {
+		return fmt.Errorf("columns must not be negative")
+	}
+	if _, _, err := c.WorkingHours(); err != nil {
+		return err
+	}
+	// Only the file's own settings, the environment overrides are checked
+	// once at startup so a bad variable doesn't make every save fail
+	if _, err := geonames.ParseDataset(c.Dataset); err != nil {
+		return err
+	}
+	if _, err := geonames.ParseBaseURL(c.GeoNamesURL); err != nil {
+		return err
+	}
+	if c.TimeFormat != "" && c.TimeFormat != TimeFormat24h && c.TimeFormat != TimeFormat12h {
+		return fmt.Errorf("invalid time_format '%s' (use %s or %s)", c.TimeFormat, TimeFormat24h, TimeFormat12h)
+	}
+	if c.Layout != "" && c.Layout != LayoutGrid && c.Layout != LayoutList {
+		return fmt.Errorf("invalid layout '%s' (use %s or %s)", c.Layout, LayoutGrid, LayoutList)
+	}
+	if c.Theme != "" && c.Theme != ThemeDark && c.Theme != ThemeLight && c.Theme != ThemeAuto {
+		return fmt.Errorf("invalid theme '%s' (use %s, %s, or %s)", c.Theme, ThemeDark, ThemeLight, ThemeAuto)
+	}
+	if c.Calendar != "" && c.Calendar != CalendarGregorian && c.Calendar != CalendarISOWeek {
+		return fmt.Errorf("invalid calendar '%s' (use %s or %s)", c.Calendar, CalendarGregorian, CalendarISOWeek)
+	}
+	switch c.Sort {
+	case "", SortOffset, SortActiveFirst, SortManual, SortName:
+	default:
+		return fmt.Errorf("invalid sort '%s' (use %s, %s, %s, or %s)", c.Sort, SortOffset, SortActiveFirst, SortManual, SortName)
+	}
+
 	// Allow empty cities list
+	homes := 0
 	for i, city := range c.Cities {
+		if city.Home {
+			homes++
+			if homes > 1 {
+				return fmt.Errorf("city '%s' is marked as home, but only one city can be", city.Name)
+			}
+		}
 		if city.Name == "" {
 			return fmt.Errorf("city at index %d has no name", i)
 		}
-		if city.Timezone == "" {
+		if city.Timezone == "" && len(city.Zones) == 0 {
 			return fmt.Errorf("city '%s' has no timezone", city.Name)
 		}
+		if city.Timezone != "" && len(city.Zones) > 0 {
+			return fmt.Errorf("city '%s' must have either timezone or zones, not both", city.Name)
+		}
 		// Validate timezone using time.LoadLocation
-		if _, err := time.LoadLocation(city.Timezone); err != nil {
-			return fmt.Errorf("invalid timezone '%s' for city '%s': %w", city.Timezone, city.Name, err)
+		if city.Timezone != "" {
+			if _, err := time.LoadLocation(city.Timezone); err != nil {
+				return fmt.Errorf("invalid timezone '%s' for city '%s': %w", city.Timezone, city.Name, err)
+			}
+		}
+		for j, zone := range city.Zones {
+			if zone.Name == "" {
+				return fmt.Errorf("zone at index %d of city '%s' has no name", j, city.Name)
+			}
+			if _, err := time.LoadLocation(zone.Timezone); zone.Timezone == "" || err != nil {
+				return fmt.Errorf("invalid timezone '%s' for zone '%s' of city '%s'", zone.Timezone, zone.Name, city.Name)
+			}
+		}
+		if city.Color != "" && !validColor(city.Color) {
+			return fmt.Errorf("invalid color '%s' for city '%s': use an ANSI color 0-255 or a hex color like #ff8800", city.Color, city.Name)
+		}
+		if city.Format != "" && !validTimeLayout(city.Format) {
+			return fmt.Errorf("invalid time format '%s' for city '%s': use Go layout fields like 15:04:05 or 3:04 PM", city.Format, city.Name)
+		}
+		// Coordinates are optional but must be complete and in range
+		if (city.Latitude == nil) != (city.Longitude == nil) {
+			return fmt.Errorf("city '%s' must have both latitude and longitude or neither", city.Name)
+		}
+		if city.Latitude != nil && (*city.Latitude < -90 || *city.Latitude > 90) {
+			return fmt.Errorf("invalid latitude %v for city '%s'", *city.Latitude, city.Name)
+		}
+		if city.Longitude != nil && (*city.Longitude < -180 || *city.Longitude > 180) {
+			return fmt.Errorf("invalid longitude %v for city '%s'", *city.Longitude, city.Name)
+		}
+		if _, _, err := c.CityWorkingHours(city); err != nil {
+			return fmt.Errorf("city '%s': %w", city.Name, err)
 		}
 	}
 
 	return nil
 }
 
-// getConfigPath returns the path to the config file
+// validTimeLayout reports whether layout formats a time into something that
+// contains time fields, rejecting typos that would render literal text
+func validTimeLayout(layout string) bool {
+	// Any field differs from the reference time, so a layout without fields
+	// formats to itself
+	sample := time.Date(2007, 2, 3, 16, 5, 6, 0, time.UTC)
+	formatted := sample.Format(layout)
+	return strings.TrimSpace(formatted) != "" && formatted != layout
+}
+
+// validColor reports whether color is an ANSI color index (0-255) or a hex
+// color (#rgb or #rrggbb)
+func validColor(color string) bool {
+	if n, err := strconv.Atoi(color); err == nil {
+		return n >= 0 && n <= 255
+	}
+	hex, ok := strings.CutPrefix(color, "#")
+	if !ok || (len(hex) != 3 && len(hex) != 6) {
+		return false
+	}
+	_, err := strconv.ParseUint(hex, 16, 32)
+	return err == nil
+}
+
+// FocusReporting reports whether the terminal should report focus changes
+func (c *Config) FocusReporting() bool {
+	return c.ReportFocus == nil || *c.ReportFocus
+}
+
+// StaleDataAge returns the city data age that triggers a warning, or 0 if
+// the warning is disabled
+func (c *Config) StaleDataAge() time.Duration {
+	days := c.StaleDataDays
+	if days < 0 {
+		return 0
+	}
+	if days == 0 {
+		days = defaultStaleDataDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// DSTWarningWindow returns how far ahead cards warn of DST changes, or 0 if
+// the warning is disabled
+func (c *Config) DSTWarningWindow() time.Duration {
+	days := c.DSTWarningDays
+	if days < 0 {
+		return 0
+	}
+	if days == 0 {
+		days = defaultDSTWarningDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// WorkingHours returns the global working hours as time since midnight,
+// defaulting to 09:00-17:00
+func (c *Config) WorkingHours() (start, end time.Duration, err error) {
+	return workingHours(c.WorkStart, c.WorkEnd, 9*time.Hour, 17*time.Hour)
+}
+
+// CityWorkingHours returns the working hours of a city, falling back to the
+// global working hours for any bound the city doesn't set
+func (c *Config) CityWorkingHours(city City) (start, end time.Duration, err error) {
+	start, end, err = c.WorkingHours()
+	if err != nil {
+		return 0, 0, err
+	}
+	return workingHours(city.WorkStart, city.WorkEnd, start, end)
+}
+
+// workingHours parses a working hours window, using the defaults for empty
+// values, and checks that it starts before it ends
+func workingHours(startText, endText string, defaultStart, defaultEnd time.Duration) (start, end time.Duration, err error) {
+	start, end = defaultStart, defaultEnd
+	if startText != "" {
+		if start, err = parseTimeOfDay(startText); err != nil {
+			return 0, 0, fmt.Errorf("invalid work_start: %w", err)
+		}
+	}
+	if endText != "" {
+		if end, err = parseTimeOfDay(endText); err != nil {
+			return 0, 0, fmt.Errorf("invalid work_end: %w", err)
+		}
+	}
+	if start >= end {
+		return 0, 0, fmt.Errorf("work_start must be before work_end")
+	}
+	return start, end, nil
+}
+
+// parseTimeOfDay parses HH:MM into the time since midnight
+// 24:00 is accepted as the end of the day
+func parseTimeOfDay(s string) (time.Duration, error) {
+	if s == "24:00" {
+		return 24 * time.Hour, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("'%s' is not a time in HH:MM format", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// WeekendHighlighting reports whether clocks on a weekend get a muted border
+func (c *Config) WeekendHighlighting() bool {
+	return c.HighlightWeekends == nil || *c.HighlightWeekends
+}
+
+// ExceedsMaxClocks reports whether more cities are configured than max_clocks allows
+func (c *Config) ExceedsMaxClocks() bool {
+	return c.MaxClocks > 0 && len(c.Cities) > c.MaxClocks
+}
+
+// ConfigEnv is the environment variable that overrides the config file path,
+// e.g. to keep separate work and home profiles
+const ConfigEnv = "WORLDCLOCK_CONFIG"
+
+// getConfigPath returns the path to the config file: $WORLDCLOCK_CONFIG if
+// set, otherwise ~/.config/worldclock.yaml
 func getConfigPath() (string, error) {
+	if env := os.Getenv(ConfigEnv); env != "" {
+		return env, nil
+	}
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return "", err
@@ -98,6 +457,23 @@ func ConfigExists() (bool, error) {
 	return true, nil
 }
 
+// ModTime returns the modification time of the config file
+// Returns the zero time if the file doesn't exist
+func ModTime() (time.Time, error) {
+	configPath, err := getConfigPath()
+	if err != nil {
+		return time.Time{}, err
+	}
+	info, err := os.Stat(configPath)
+	if os.IsNotExist(err) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
 // createDefaultConfig creates a default configuration file with system timezone
 func createDefaultConfig(path string) error {
 	return createDefaultConfigWithCity(path, "Local")
@@ -105,12 +481,6 @@ func createDefaultConfig(path string) error {
 
 // createDefaultConfigWithCity creates a default configuration file with specified city name
 func createDefaultConfigWithCity(path, cityName string) error {
-	// Create .config directory if it doesn't exist
-	configDir := filepath.Dir(path)
-	if err := os.MkdirAll(configDir, 0755); err != nil {
-		return err
-	}
-
 	// Get system timezone
 	systemTZ := getSystemTimezone()
 
@@ -121,18 +491,8 @@ func createDefaultConfigWithCity(path, cityName string) error {
 		},
 	}
 
-	// Marshal to YAML
-	data, err := yaml.Marshal(&defaultConfig)
-	if err != nil {
-		return err
-	}
-
-	// Write to file
-	if err := os.WriteFile(path, data, 0644); err != nil {
-		return err
-	}
-
-	return nil
+	// Save creates the .config directory if it doesn't exist
+	return defaultConfig.saveTo(path)
 }
 
 // CreateDefaultConfigWithCity is the exported version for use by main package
@@ -161,13 +521,18 @@ func GetSystemTimezone() string {
 	return getSystemTimezone()
 }
 
-// Save writes the configuration to ~/.config/worldclock.yaml atomically
+// Save writes the configuration to ~/.config/worldclock.yaml or
+// $WORLDCLOCK_CONFIG atomically
 func (c *Config) Save() error {
 	configPath, err := getConfigPath()
 	if err != nil {
 		return fmt.Errorf("failed to get config path: %w", err)
 	}
+	return c.saveTo(configPath)
+}
 
+// saveTo writes the configuration to configPath atomically
+func (c *Config) saveTo(configPath string) error {
 	// Validate before saving
 	if err := c.Validate(); err != nil {
 		return fmt.Errorf("invalid config: %w", err)
@@ -180,7 +545,11 @@ func (c *Config) Save() error {
 	}
 
 	// Atomic write: write to temp file, then rename
+	// The directory may not exist yet when the first city is added
 	configDir := filepath.Dir(configPath)
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
 	tempFile, err := os.CreateTemp(configDir, "worldclock-*.yaml.tmp")
 	if err != nil {
 		return fmt.Errorf("failed to create temp file: %w", err)
@@ -209,41 +578,133 @@ func (c *Config) Save() error {
 	return nil
 }
 
+// Reset backs up the config file to a timestamped copy next to it and
+// replaces it with the default configuration
+// Returns the path of the backup, or "" if there was no config file
+func Reset() (string, error) {
+	configPath, err := getConfigPath()
+	if err != nil {
+		return "", fmt.Errorf("failed to get config path: %w", err)
+	}
+
+	backupPath, err := backupFile(configPath, time.Now())
+	if err != nil {
+		return "", fmt.Errorf("failed to back up config: %w", err)
+	}
+
+	if err := createDefaultConfig(configPath); err != nil {
+		return backupPath, fmt.Errorf("failed to create default config: %w", err)
+	}
+
+	return backupPath, nil
+}
+
+// backupFile copies path to path.YYYYMMDD-HHMMSS.bak
+// Returns "" if path doesn't exist
+func backupFile(path string, now time.Time) (string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	backupPath := fmt.Sprintf("%s.%s.bak", path, now.Format("20060102-150405"))
+	if err := os.WriteFile(backupPath, data, 0644); err != nil {
+		return "", err
+	}
+	return backupPath, nil
+}
+
+// Path returns the path of the config file
+func Path() (string, error) {
+	return getConfigPath()
+}
+
+// ErrDuplicateTimezone matches the DuplicateTimezoneError returned when a
+// city would show a timezone that another city already shows
+var ErrDuplicateTimezone = errors.New("timezone already shown")
+
+// DuplicateTimezoneError reports the city already showing a timezone
+type DuplicateTimezoneError struct {
+	Timezone string
+	Existing City
+}
+
+// Error describes the duplicate, e.g. "America/New_York already shown as 'NYC'"
+func (e *DuplicateTimezoneError) Error() string {
+	return fmt.Sprintf("%s already shown as '%s'", e.Timezone, e.Existing.DisplayName())
+}
+
+// Is makes errors.Is match ErrDuplicateTimezone
+func (e *DuplicateTimezoneError) Is(target error) bool {
+	return target == ErrDuplicateTimezone
+}
+
 // AddCity adds a new city to the configuration
+// Returns a DuplicateTimezoneError if another city already shows the timezone,
+// use AddCityAnyway to add it regardless
 func (c *Config) AddCity(name, timezone string) error {
+	return c.addCity(City{Name: name, Timezone: timezone}, false)
+}
+
+// AddCityAt adds a new city with known coordinates to the configuration, like
+// AddCity
+func (c *Config) AddCityAt(name, timezone string, latitude, longitude float64) error {
+	return c.addCity(City{Name: name, Timezone: timezone, Latitude: &latitude, Longitude: &longitude}, false)
+}
+
+// AddCityAnyway adds a city even if another city already shows its timezone
+func (c *Config) AddCityAnyway(city City) error {
+	return c.addCity(city, true)
+}
+
+// addCity adds city after checking it, allowSameTimezone skips the check for
+// other cities in the same timezone
+func (c *Config) addCity(city City, allowSameTimezone bool) error {
 	// Check if city already exists
-	for _, city := range c.Cities {
-		if city.Name == name && city.Timezone == timezone {
-			return fmt.Errorf("city '%s' already exists", name)
-		}
+	if c.HasCity(city) {
+		return fmt.Errorf("city '%s' already exists", city.Name)
 	}
 
 	// Validate timezone
-	if _, err := time.LoadLocation(timezone); err != nil {
-		return fmt.Errorf("invalid timezone '%s': %w", timezone, err)
+	if _, err := time.LoadLocation(city.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone '%s': %w", city.Timezone, err)
+	}
+
+	// Enforce the optional clock limit, before asking about duplicates a
+	// full list couldn't take anyway
+	if c.MaxClocks > 0 && len(c.Cities) >= c.MaxClocks {
+		return fmt.Errorf("cannot add '%s': limit of %d clocks reached (max_clocks in config)", city.Name, c.MaxClocks)
+	}
+
+	if !allowSameTimezone {
+		for _, existing := range c.Cities {
+			if existing.showsTimezone(city.Timezone) {
+				return &DuplicateTimezoneError{Timezone: city.Timezone, Existing: existing}
+			}
+		}
 	}
 
 	// Add city
-	c.Cities = append(c.Cities, City{
-		Name:     name,
-		Timezone: timezone,
-	})
+	c.Cities = append(c.Cities, city)
 
 	return nil
 }
 
-// DeleteCities removes cities by name from the configuration
-func (c *Config) DeleteCities(names []string) error {
-	// Create a map for quick lookup
-	toDelete := make(map[string]bool)
-	for _, name := range names {
-		toDelete[name] = true
+// DeleteCities removes the cities at the given indices from the
+// configuration, so other clocks of the same city are kept
+func (c *Config) DeleteCities(indices []int) error {
+	for _, index := range indices {
+		if index < 0 || index >= len(c.Cities) {
+			return fmt.Errorf("city %d does not exist", index)
+		}
 	}
 
-	// Filter cities
 	var remaining []City
-	for _, city := range c.Cities {
-		if !toDelete[city.Name] {
+	for i, city := range c.Cities {
+		if !slices.Contains(indices, i) {
 			remaining = append(remaining, city)
 		}
 	}
@@ -252,12 +713,30 @@ func (c *Config) DeleteCities(names []string) error {
 	return nil
 }
 
-// HasCity checks if a city with the given name exists
-func (c *Config) HasCity(name string) bool {
-	for _, city := range c.Cities {
-		if city.Name == name {
-			return true
+// UpdateCity replaces the city at index, e.g. after it was renamed or moved
+// to another timezone
+func (c *Config) UpdateCity(index int, city City) error {
+	if index < 0 || index >= len(c.Cities) {
+		return fmt.Errorf("city %d does not exist", index)
+	}
+	if strings.TrimSpace(city.Name) == "" {
+		return fmt.Errorf("city name must not be empty")
+	}
+	if len(city.Zones) == 0 {
+		if _, err := time.LoadLocation(city.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone '%s': %w", city.Timezone, err)
 		}
 	}
-	return false
+	for i, other := range c.Cities {
+		if i != index && other.Same(city) {
+			return fmt.Errorf("city '%s' already exists", city.DisplayName())
+		}
+	}
+	c.Cities[index] = city
+	return nil
+}
+
+// HasCity checks if the config has the same city, see City.Same
+func (c *Config) HasCity(city City) bool {
+	return slices.ContainsFunc(c.Cities, city.Same)
 }
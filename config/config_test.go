@@ -0,0 +1,106 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestCitySame(t *testing.T) {
+	lat, lon := 40.71427, -74.00597
+	otherLat := 40.0
+	newYork := City{Name: "New York", Timezone: "America/New_York"}
+
+	tests := []struct {
+		name  string
+		other City
+		want  bool
+	}{
+		{"labelled", City{Name: "New York", Timezone: "America/New_York", Label: "HQ"}, false},
+		{"other name", City{Name: "New York HQ", Timezone: "America/New_York"}, false},
+		{"other timezone", City{Name: "New York", Timezone: "America/Chicago"}, false},
+		{"coordinates on one side", City{Name: "New York", Timezone: "America/New_York", Latitude: &lat, Longitude: &lon}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newYork.Same(tt.other); got != tt.want {
+				t.Errorf("Same() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Cities with coordinates on both sides must be at the same place
+	a := City{Name: "Springfield", Timezone: "America/Chicago", Latitude: &lat, Longitude: &lon}
+	b := City{Name: "Springfield", Timezone: "America/Chicago", Latitude: &otherLat, Longitude: &lon}
+	if a.Same(b) {
+		t.Error("Same() = true for cities at different coordinates")
+	}
+}
+
+func TestAddLabelledCopies(t *testing.T) {
+	cfg := &Config{Cities: []City{{Name: "New York", Label: "HQ", Timezone: "America/New_York"}}}
+
+	// Another label is another clock, the same one is a duplicate
+	if err := cfg.AddCityAnyway(City{Name: "New York", Label: "Client", Timezone: "America/New_York"}); err != nil {
+		t.Errorf("AddCityAnyway() with another label error = %v", err)
+	}
+	if err := cfg.AddCityAnyway(City{Name: "New York", Label: "HQ", Timezone: "America/New_York"}); err == nil {
+		t.Error("AddCityAnyway() with the same label didn't fail")
+	}
+}
+
+func TestValidateCalendar(t *testing.T) {
+	for _, calendar := range []string{"", CalendarGregorian, CalendarISOWeek} {
+		cfg := &Config{Calendar: calendar}
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("Validate() with calendar %q error = %v", calendar, err)
+		}
+	}
+
+	cfg := &Config{Calendar: "julian"}
+	err := cfg.Validate()
+	if want := "invalid calendar 'julian' (use gregorian or iso-week)"; err == nil || err.Error() != want {
+		t.Errorf("Validate() error = %v, want %q", err, want)
+	}
+}
+
+func TestSaveIgnoresBadGeoNamesEnv(t *testing.T) {
+	t.Setenv(ConfigEnv, filepath.Join(t.TempDir(), "worldclock.yaml"))
+	t.Setenv(GeoNamesDatasetEnv, "cities42")
+	t.Setenv(GeoNamesURLEnv, "not a url")
+
+	// The overrides aren't part of the file, saving it still works
+	cfg := &Config{Cities: []City{{Name: "Berlin", Timezone: "Europe/Berlin"}}}
+	if err := cfg.Save(); err != nil {
+		t.Errorf("Save() error = %v", err)
+	}
+
+	// They are reported when read
+	if _, err := cfg.GeoNamesDataset(); err == nil {
+		t.Errorf("GeoNamesDataset() with %s=cities42 didn't fail", GeoNamesDatasetEnv)
+	}
+	if _, err := cfg.GeoNamesBaseURL(); err == nil {
+		t.Errorf("GeoNamesBaseURL() with %s=%q didn't fail", GeoNamesURLEnv, "not a url")
+	}
+}
+
+func TestAddCityChecks(t *testing.T) {
+	office := City{Name: "Office", Zones: []Zone{
+		{Name: "HQ", Timezone: "Europe/Berlin"},
+		{Name: "Support", Timezone: "Asia/Tokyo"},
+	}}
+
+	// A timezone shown as a zone is a duplicate too
+	cfg := &Config{Cities: []City{office}}
+	err := cfg.AddCity("Osaka", "Asia/Tokyo")
+	var dup *DuplicateTimezoneError
+	if !errors.As(err, &dup) || dup.Existing.Name != "Office" {
+		t.Errorf("AddCity() of a zone's timezone error = %v, want a duplicate of Office", err)
+	}
+
+	// A full list fails on the limit without asking about the duplicate
+	cfg = &Config{Cities: []City{office}, MaxClocks: 1}
+	if err := cfg.AddCity("Osaka", "Asia/Tokyo"); err == nil || errors.Is(err, ErrDuplicateTimezone) {
+		t.Errorf("AddCity() to a full list error = %v, want the max_clocks error", err)
+	}
+}
@@ -0,0 +1,64 @@
+package geonames
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Dataset is a GeoNames cities dump, named after its minimum population
+type Dataset string
+
+// Available datasets, smaller minimum populations include more towns
+const (
+	Cities15000 Dataset = "cities15000"
+	Cities5000  Dataset = "cities5000"
+	Cities1000  Dataset = "cities1000"
+	Cities500   Dataset = "cities500"
+
+	// DefaultDataset is used when no dataset is configured
+	DefaultDataset = Cities15000
+)
+
+// DefaultBaseURL is where the GeoNames dumps are downloaded from unless a
+// mirror is configured
+const DefaultBaseURL = "https://download.geonames.org/export/dump/"
+
+// ParseDataset returns the dataset with the given name, or the default
+// dataset for an empty name
+func ParseDataset(name string) (Dataset, error) {
+	switch d := Dataset(name); d {
+	case "":
+		return DefaultDataset, nil
+	case Cities15000, Cities5000, Cities1000, Cities500:
+		return d, nil
+	}
+	return "", fmt.Errorf("unknown dataset '%s' (use %s, %s, %s, or %s)", name, Cities15000, Cities5000, Cities1000, Cities500)
+}
+
+// ParseBaseURL checks that a mirror URL is absolute and returns it with a
+// trailing slash, or the default base URL for an empty string
+func ParseBaseURL(raw string) (string, error) {
+	if raw == "" {
+		return DefaultBaseURL, nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil || !u.IsAbs() || u.Host == "" {
+		return "", fmt.Errorf("invalid GeoNames URL '%s' (use an absolute URL like %s)", raw, DefaultBaseURL)
+	}
+	if !strings.HasSuffix(raw, "/") {
+		raw += "/"
+	}
+	return raw, nil
+}
+
+// URL returns the download URL of the zipped dataset below baseURL
+func (d Dataset) URL(baseURL string) string {
+	return baseURL + string(d) + ".zip"
+}
+
+// FileName returns the name of the dataset inside the zip file, which is also
+// the name of its cache file
+func (d Dataset) FileName() string {
+	return string(d) + ".txt"
+}
@@ -3,87 +3,334 @@ package geonames
 import (
 	"archive/zip"
 	"bufio"
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
+	"unicode/utf8"
 )
 
 const (
-	// GeoNamesURL is the download URL for cities with 15000+ population
-	GeoNamesURL = "http://download.geonames.org/export/dump/cities15000.zip"
-	// CacheFileName is the name of the cached cities file
-	CacheFileName = "cities15000.txt"
+	// geoNamesColumns is the number of tab-separated columns in the dump
+	geoNamesColumns = 19
+
+	// MaxDownloadAttempts is how often the download is tried before giving up
+	MaxDownloadAttempts = 3
+	// downloadBackoff is the wait before the first retry, doubled for each
+	// further retry
+	downloadBackoff = time.Second
 )
 
+// DownloadTimeout is how long a download attempt waits for the server to
+// respond, or for more data once it does, so a stalled connection doesn't
+// keep the background loader waiting. Slow downloads that keep making
+// progress are never cut off
+var DownloadTimeout = 30 * time.Second
+
+// errDownloadStalled is the cause of an attempt given up on because no data
+// arrived for DownloadTimeout
+var errDownloadStalled = errors.New("download stalled")
+
+// embeddedCities is a trimmed set of capital cities in the GeoNames format,
+// used when the full dataset can't be downloaded
+//
+//go:embed capitals.txt
+var embeddedCities []byte
+
 // City represents a city from the GeoNames database
 type City struct {
 	Name        string
+	ASCIIName   string // Name in plain ASCII letters, e.g. "Zurich" for "Zürich"
 	CountryCode string
+	Admin1      string // First-level administrative division code, e.g. "OR" for Oregon
 	Timezone    string
 	Population  int
+	Latitude    float64
+	Longitude   float64
+
+	// Comma-separated other names and translations, e.g. "Cologne" for "Köln",
+	// only kept until the search indexes are built
+	alternateNames string
 }
 
 // Database holds the GeoNames cities data
+// It is safe for concurrent use: loading runs in the background while the UI
+// searches, so every access to its fields goes through mu
 type Database struct {
-	cities []City
-	ready  bool
-	err    error
-	mu     sync.RWMutex
+	dataset     Dataset
+	baseURL     string // Where the dataset is downloaded from
+	cities      []City
+	searchNames [][]string       // Folded names, ASCII names, and alternate names of each city, by index
+	prefixIndex map[string][]int // Indices of cities by the first prefixLength letters of any of their names
+	ready       bool
+	embedded    bool // Loaded from the bundled capital cities instead of the full dataset
+	offline     bool // Created from the bundled capital cities, never downloads
+	downloads   int  // Loaders downloading the full dataset
+	err         error
+	mu          sync.RWMutex
+
+	// Download progress in bytes, total is -1 if the size is unknown
+	downloaded int64
+	total      int64
+
+	// Background loading is cancelled and awaited by Close
+	ctx     context.Context
+	cancel  context.CancelFunc
+	loaders sync.WaitGroup
 }
 
-// NewDatabase creates a new GeoNames database instance
-func NewDatabase() *Database {
+// NewDatabase creates a new GeoNames database instance for a dataset,
+// downloaded from baseURL or the default location if it is empty
+func NewDatabase(dataset Dataset, baseURL string) *Database {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Database{
-		cities: []City{},
-		ready:  false,
+		dataset: dataset,
+		baseURL: baseURL,
+		cities:  []City{},
+		ready:   false,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// NewDatabaseFromReader creates a database from GeoNames records read from r,
+// ready for use without downloading anything
+func NewDatabaseFromReader(r io.Reader) (*Database, error) {
+	cities, err := parseCities(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse GeoNames data: %w", err)
 	}
+	db := NewDatabase(DefaultDataset, "")
+	db.setCities(cities, false)
+	return db, nil
+}
+
+// NewEmbeddedDatabase creates a database from the capital cities bundled
+// into the binary, for machines without network access
+func NewEmbeddedDatabase() (*Database, error) {
+	db := NewDatabase(DefaultDataset, "")
+	if err := db.loadEmbedded(); err != nil {
+		return nil, err
+	}
+	db.offline = true
+	return db, nil
 }
 
 // LoadAsync loads the GeoNames database asynchronously
 func (db *Database) LoadAsync() {
+	db.loadInBackground(false)
+}
+
+// loadInBackground runs load in a goroutine that Close waits for
+func (db *Database) loadInBackground(refresh bool) {
+	db.loaders.Add(1)
 	go func() {
-		if err := db.load(); err != nil {
-			db.mu.Lock()
-			db.err = err
-			db.mu.Unlock()
-		}
+		defer db.loaders.Done()
+		downloaded, err := db.load(db.ctx, refresh)
+		db.finishLoading(err, downloaded)
 	}()
 }
 
-// load downloads (if needed) and loads the GeoNames database
-func (db *Database) load() error {
-	cachePath, err := getCachePath()
+// Close cancels a download in progress and waits for background loading to
+// stop, so no partial download is left behind. The cities already loaded stay
+// searchable
+func (db *Database) Close() {
+	db.cancel()
+	db.loaders.Wait()
+}
+
+// finishLoading records the outcome of load
+// A download load counted ends in the same step, so that callers never see
+// it finished without its error. Other loaders leave it running
+func (db *Database) finishLoading(err error, downloaded bool) {
+	db.mu.Lock()
+	db.err = err
+	if downloaded {
+		db.downloads--
+	}
+	db.mu.Unlock()
+}
+
+// load downloads (if needed, or always to refresh) and loads the GeoNames
+// database, giving up on the download when ctx is cancelled. It reports
+// whether the load is counted as a download, which Refresh counts up front
+func (db *Database) load(ctx context.Context, refresh bool) (bool, error) {
+	cachePath, err := db.CachePath()
 	if err != nil {
-		return fmt.Errorf("failed to get cache path: %w", err)
+		return refresh, fmt.Errorf("failed to get cache path: %w", err)
 	}
 
 	// Check if cache file exists
-	if _, err := os.Stat(cachePath); os.IsNotExist(err) {
-		// Download and extract
-		if err := downloadAndExtract(cachePath); err != nil {
-			return fmt.Errorf("failed to download GeoNames data: %w", err)
+	downloaded := refresh
+	if _, err := os.Stat(cachePath); os.IsNotExist(err) || refresh {
+		// Search the bundled capitals right away while the full dataset
+		// downloads, and keep them if the download fails. The download is
+		// marked first, so the capitals never look like the finished database
+		// A refresh keeps searching the cities already loaded
+		db.mu.Lock()
+		if !refresh {
+			db.downloads++
+			downloaded = true
+		}
+		ready := db.ready
+		db.mu.Unlock()
+		if !ready {
+			if err := db.loadEmbedded(); err != nil {
+				return downloaded, err
+			}
+		}
+		if err := db.download(ctx, cachePath); err != nil {
+			return downloaded, err
 		}
 	}
 
 	// Parse the file
 	cities, err := parseFile(cachePath)
 	if err != nil {
-		return fmt.Errorf("failed to parse GeoNames data: %w", err)
+		return downloaded, fmt.Errorf("failed to parse GeoNames data: %w", err)
 	}
+	db.setCities(cities, false)
+
+	return downloaded, nil
+}
+
+// download fetches the dataset into a file next to the cache and renames it
+// over the cached file once it is complete and valid, so a failed download
+// keeps the cities cached before
+func (db *Database) download(ctx context.Context, cachePath string) error {
+	tempPath := cachePath + ".download"
+	defer os.Remove(tempPath) // Clean up after a failed download
+	if err := downloadAndExtract(ctx, db.baseURL, db.dataset, tempPath, db.setProgress); err != nil {
+		return fmt.Errorf("download failed, check your connection: %w", err)
+	}
+	// Reject corrupted or tampered downloads instead of parsing garbage
+	if err := verifyCitiesFile(tempPath); err != nil {
+		return fmt.Errorf("downloaded GeoNames data is invalid: %w", err)
+	}
+	if err := os.Rename(tempPath, cachePath); err != nil {
+		return fmt.Errorf("failed to replace cached city data: %w", err)
+	}
+	return nil
+}
+
+// Refresh downloads the cities file again in the background, so new or
+// renamed cities appear. The cities already loaded stay searchable, and the
+// cached file is only replaced once the download succeeds
+func (db *Database) Refresh() error {
+	db.mu.Lock()
+	if db.offline {
+		db.mu.Unlock()
+		return fmt.Errorf("the city data isn't downloaded in offline mode")
+	}
+	if db.downloads > 0 {
+		db.mu.Unlock()
+		return fmt.Errorf("the city data is already being downloaded")
+	}
+	db.downloads++
+	db.downloaded, db.total = 0, 0
+	db.err = nil
+	db.mu.Unlock()
+
+	db.loadInBackground(true)
+	return nil
+}
+
+// setCities makes cities searchable, replacing the ones loaded before
+// The search indexes are built before locking, so searches aren't blocked
+func (db *Database) setCities(cities []City, embedded bool) {
+	searchNames := make([][]string, len(cities))
+	for i := range cities {
+		searchNames[i] = cityNames(&cities[i], foldName(cities[i].Name))
+		cities[i].alternateNames = ""
+	}
+	prefixIndex := buildPrefixIndex(searchNames)
 
 	db.mu.Lock()
 	db.cities = cities
+	db.searchNames = searchNames
+	db.prefixIndex = prefixIndex
 	db.ready = true
+	db.embedded = embedded
 	db.mu.Unlock()
+}
+
+// setProgress records how much of the download is done
+func (db *Database) setProgress(downloaded, total int64) {
+	db.mu.Lock()
+	db.downloaded = downloaded
+	db.total = total
+	db.mu.Unlock()
+}
+
+// DownloadedBytes returns how many bytes of the GeoNames data were
+// downloaded, and the size of the download or -1 if it's unknown
+// Both are 0 until a download starts
+func (db *Database) DownloadedBytes() (downloaded, total int64) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.downloaded, db.total
+}
+
+// Progress returns the downloaded fraction of the GeoNames data from 0 to 1,
+// or -1 if the size of the download is unknown
+func (db *Database) Progress() float64 {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	if db.total <= 0 {
+		return -1
+	}
+	return float64(db.downloaded) / float64(db.total)
+}
+
+// loadEmbedded loads the capital cities bundled into the binary
+func (db *Database) loadEmbedded() error {
+	cities, err := parseCities(bytes.NewReader(embeddedCities))
+	if err != nil {
+		return fmt.Errorf("failed to parse embedded GeoNames data: %w", err)
+	}
+	db.setCities(cities, true)
 
 	return nil
 }
 
+// IsDownloading reports whether the full dataset is still being downloaded
+// Until it is done, only the bundled capital cities can be searched
+func (db *Database) IsDownloading() bool {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.downloads > 0
+}
+
+// IsOffline reports whether the database only has the bundled capital cities
+// and never downloads the full dataset
+func (db *Database) IsOffline() bool {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.offline
+}
+
+// IsEmbedded reports whether only the bundled capital cities are loaded
+func (db *Database) IsEmbedded() bool {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.embedded
+}
+
 // IsReady returns whether the database is loaded and ready
 func (db *Database) IsReady() bool {
 	db.mu.RLock()
@@ -99,6 +346,8 @@ func (db *Database) GetError() error {
 }
 
 // Search searches for cities matching the query
+// A query naming a country ("japan" or "jp") also returns its largest cities,
+// and a country after a comma ("tokyo, jp") only matches cities in it
 // Returns top maxResults matches
 func (db *Database) Search(query string, maxResults int) []City {
 	db.mu.RLock()
@@ -108,36 +357,100 @@ func (db *Database) Search(query string, maxResults int) []City {
 		return []City{}
 	}
 
-	query = strings.ToLower(strings.TrimSpace(query))
-	if len(query) < 3 {
+	query = foldName(strings.TrimSpace(query))
+
+	// Filter by the country after a comma, ignored until one is typed
+	var countries map[string]bool
+	if name, country, found := strings.Cut(query, ","); found {
+		query = strings.TrimSpace(name)
+		if country = strings.TrimSpace(country); country != "" {
+			countries = matchCountries(country)
+		}
+	}
+
+	// List the largest cities of a country named by the query
+	var countryCities []City
+	if code := countryCode(query); code != "" && countries == nil {
+		countryCities = db.largestCities(code, maxResults)
+	}
+
+	if len(query) < 3 && len(countryCities) == 0 {
 		return []City{}
 	}
 
 	var exactMatches []City
 	var partialMatches []City
 
-	for _, city := range db.cities {
-		cityNameLower := strings.ToLower(city.Name)
+	// match sorts a city into the exact or partial matches, and reports
+	// whether there are enough results
+	match := func(i int) bool {
+		city := &db.cities[i] // Not copied, most cities don't match
+		if countries != nil && !countries[city.CountryCode] {
+			return false
+		}
+		// Any of the city's names can match, the best match counts
+		exact, partial := false, false
+		for _, name := range db.searchNames[i] {
+			if name == query {
+				// Exact match
+				exact = true
+				break
+			}
+			if strings.Contains(name, query) {
+				// Prefix or contains match
+				partial = true
+			}
+		}
+		if exact {
+			exactMatches = append(exactMatches, *city)
+		} else if partial {
+			partialMatches = append(partialMatches, *city)
+		}
+		return len(exactMatches)+len(partialMatches) >= maxResults
+	}
 
-		// Exact match
-		if cityNameLower == query {
-			exactMatches = append(exactMatches, city)
-		} else if strings.HasPrefix(cityNameLower, query) {
-			// Prefix match
-			partialMatches = append(partialMatches, city)
-		} else if strings.Contains(cityNameLower, query) {
-			// Contains match
-			partialMatches = append(partialMatches, city)
+	if len(query) >= 3 {
+		// Exact and prefix matches all share the query's first letters, so
+		// those cities are scanned first
+		bucket := db.prefixIndex[prefixKey(query)]
+		enough := false
+		for _, i := range bucket {
+			if enough = match(i); enough {
+				break
+			}
 		}
 
-		// Stop if we have enough results
-		if len(exactMatches)+len(partialMatches) >= maxResults {
-			break
+		// Then every other name is searched for the query anywhere in it
+		if !enough {
+			scanned := make(map[int]bool, len(bucket))
+			for _, i := range bucket {
+				scanned[i] = true
+			}
+			for i := range db.cities {
+				if !scanned[i] && match(i) {
+					break
+				}
+			}
 		}
 	}
 
-	// Combine results: exact matches first, then partial
+	// Combine results: exact matches first, then partial, then the country's
+	// largest cities
 	results := append(exactMatches, partialMatches...)
+	for _, city := range countryCities {
+		if !slices.Contains(results, city) {
+			results = append(results, city)
+		}
+	}
+
+	// Allow for typos when there are few precise matches, ranked last
+	if len(results) < fuzzyThreshold && len([]rune(query)) > maxFuzzyDistance+1 {
+		for _, city := range db.fuzzyMatches(query, countries, maxResults) {
+			if !slices.Contains(results, city) {
+				results = append(results, city)
+			}
+		}
+	}
 	if len(results) > maxResults {
 		results = results[:maxResults]
 	}
@@ -145,6 +458,191 @@ func (db *Database) Search(query string, maxResults int) []City {
 	return results
 }
 
+// Fuzzy matching
+const (
+	fuzzyThreshold   = 5 // Fewer precise matches than this add fuzzy matches
+	maxFuzzyDistance = 2 // Most edits between the query and a fuzzy match
+)
+
+// fuzzyMatches returns up to maxResults cities with a name (including ASCII
+// and alternate names) at most maxFuzzyDistance edits away from the folded
+// query, closest first
+// countries, if not nil, limits the matches to those countries
+func (db *Database) fuzzyMatches(query string, countries map[string]bool, maxResults int) []City {
+	type match struct {
+		city     City
+		distance int
+	}
+	q := []rune(query)
+	var matches []match
+	// Reused for every name, so the scan doesn't allocate per city
+	var name []rune
+	var rows []int
+	for i := range db.cities {
+		city := &db.cities[i] // Not copied, most cities don't match
+		if countries != nil && !countries[city.CountryCode] {
+			continue
+		}
+		// The closest of the city's names counts
+		best := maxFuzzyDistance + 1
+		for _, searchName := range db.searchNames[i] {
+			// Most names differ too much in length to be close, skip them
+			// before the costlier distance computation
+			if diff := utf8.RuneCountInString(searchName) - len(q); diff > maxFuzzyDistance || diff < -maxFuzzyDistance {
+				continue
+			}
+			name = name[:0]
+			for _, r := range searchName {
+				name = append(name, r)
+			}
+			var d int
+			if d, rows = editDistance(q, name, maxFuzzyDistance, rows); d < best {
+				best = d
+			}
+		}
+		if best <= maxFuzzyDistance {
+			matches = append(matches, match{*city, best})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].distance < matches[j].distance
+	})
+
+	var cities []City
+	for _, m := range matches {
+		if len(cities) >= maxResults {
+			break
+		}
+		cities = append(cities, m.city)
+	}
+	return cities
+}
+
+// editDistance returns the Levenshtein distance between a and b, or limit+1 as
+// soon as it's certain to exceed limit
+// rows is scratch space that is grown as needed and returned for reuse
+func editDistance(a, b []rune, limit int, rows []int) (int, []int) {
+	if len(a)-len(b) > limit || len(b)-len(a) > limit {
+		return limit + 1, rows
+	}
+
+	if cap(rows) < 2*(len(b)+1) {
+		rows = make([]int, 2*(len(b)+1))
+	}
+	rows = rows[:2*(len(b)+1)]
+	prev, curr := rows[:len(b)+1], rows[len(b)+1:]
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, curr[j])
+		}
+		// Every later row is at least as large as this row's minimum
+		if rowMin > limit {
+			return limit + 1, rows
+		}
+		prev, curr = curr, prev
+	}
+	return min(prev[len(b)], limit+1), rows
+}
+
+// prefixLength is the number of letters of a name used as prefix index key
+const prefixLength = 3
+
+// buildPrefixIndex maps the first letters of each of the cities' folded names
+// to the indices of the cities, in their original order and without repeats
+func buildPrefixIndex(searchNames [][]string) map[string][]int {
+	index := make(map[string][]int)
+	for i, names := range searchNames {
+		for _, name := range names {
+			key := prefixKey(name)
+			if key == "" {
+				continue
+			}
+			if bucket := index[key]; len(bucket) == 0 || bucket[len(bucket)-1] != i {
+				index[key] = append(bucket, i)
+			}
+		}
+	}
+	return index
+}
+
+// cityNames returns the distinct folded names a city can be found by, starting
+// with its folded name
+// Links that GeoNames lists among the alternate names are left out
+func cityNames(city *City, lowerName string) []string {
+	names := []string{lowerName}
+	add := func(name string) {
+		name = foldName(strings.TrimSpace(name))
+		if name != "" && !strings.Contains(name, "://") && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	add(city.ASCIIName)
+	for name := range strings.SplitSeq(city.alternateNames, ",") {
+		add(name)
+	}
+	return names
+}
+
+// prefixKey returns the first prefixLength letters of a lowercase name, or ""
+// if it's shorter
+func prefixKey(name string) string {
+	runes := []rune(name)
+	if len(runes) < prefixLength {
+		return ""
+	}
+	return string(runes[:prefixLength])
+}
+
+// largestCities returns up to maxResults cities of a country, by population
+func (db *Database) largestCities(countryCode string, maxResults int) []City {
+	var cities []City
+	for _, city := range db.cities {
+		if city.CountryCode == countryCode {
+			cities = append(cities, city)
+		}
+	}
+	sort.SliceStable(cities, func(i, j int) bool {
+		return cities[i].Population > cities[j].Population
+	})
+	if len(cities) > maxResults {
+		cities = cities[:maxResults]
+	}
+	return cities
+}
+
+// countryCode returns the code of the country named by a folded query,
+// either its ISO code ("jp") or its name ("japan"), or "" if there is none
+func countryCode(query string) string {
+	for code, name := range foldedCountryNames {
+		if query == strings.ToLower(code) || query == name {
+			return code
+		}
+	}
+	return ""
+}
+
+// matchCountries returns the codes of the countries whose ISO code is the
+// folded query or whose name starts with it, so "jap" already filters
+func matchCountries(query string) map[string]bool {
+	codes := make(map[string]bool)
+	for code, name := range foldedCountryNames {
+		if query == strings.ToLower(code) || strings.HasPrefix(name, query) {
+			codes[code] = true
+		}
+	}
+	return codes
+}
+
 // FindBestCityForTimezone finds the most populous city in the given timezone
 // Returns the city name, or "Local" if no city is found
 func (db *Database) FindBestCityForTimezone(timezone string) string {
@@ -173,24 +671,113 @@ func (db *Database) FindBestCityForTimezone(timezone string) string {
 	return "Local"
 }
 
+// Suggest returns up to maxResults other major cities in the same country as
+// city. The most populous city of each other timezone comes first, followed by
+// the remaining cities by population
+func (db *Database) Suggest(city City, maxResults int) []City {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	if !db.ready {
+		return []City{}
+	}
+
+	// Collect other cities in the same country, largest first
+	var candidates []City
+	for _, c := range db.cities {
+		if c.CountryCode == city.CountryCode && !(c.Name == city.Name && c.Timezone == city.Timezone) {
+			candidates = append(candidates, c)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Population > candidates[j].Population
+	})
+
+	// First pass: one city per timezone not yet covered
+	var results []City
+	used := make([]bool, len(candidates))
+	seenTimezones := map[string]bool{city.Timezone: true}
+	for i, c := range candidates {
+		if len(results) >= maxResults {
+			return results
+		}
+		if !seenTimezones[c.Timezone] {
+			seenTimezones[c.Timezone] = true
+			used[i] = true
+			results = append(results, c)
+		}
+	}
+
+	// Second pass: fill up with the largest remaining cities
+	for i, c := range candidates {
+		if len(results) >= maxResults {
+			break
+		}
+		if !used[i] {
+			results = append(results, c)
+		}
+	}
+
+	return results
+}
+
+// Timezones returns the distinct timezones of all cities, sorted
+func (db *Database) Timezones() []string {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var timezones []string
+	for _, city := range db.cities {
+		if !seen[city.Timezone] {
+			seen[city.Timezone] = true
+			timezones = append(timezones, city.Timezone)
+		}
+	}
+	sort.Strings(timezones)
+	return timezones
+}
+
 // LoadSync loads the GeoNames database synchronously (blocking)
 func (db *Database) LoadSync() error {
-	return db.load()
+	downloaded, err := db.load(db.ctx, false)
+	db.finishLoading(err, downloaded)
+	return err
+}
+
+// Dataset returns the dataset the database loads
+func (db *Database) Dataset() Dataset {
+	return db.dataset
 }
 
-// getCachePath returns the path to the cache file
-func getCachePath() (string, error) {
+// CachePath returns the path of the cached cities file
+// Each dataset has its own file, so switching doesn't reuse another's data
+func (db *Database) CachePath() (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return "", err
 	}
 
 	cacheDir := filepath.Join(homeDir, ".cache", "worldclock")
-	return filepath.Join(cacheDir, CacheFileName), nil
+	return filepath.Join(cacheDir, db.dataset.FileName()), nil
 }
 
-// downloadAndExtract downloads the GeoNames zip file and extracts it
-func downloadAndExtract(targetPath string) error {
+// CacheModTime returns when the cached cities file was last downloaded
+func (db *Database) CacheModTime() (time.Time, error) {
+	cachePath, err := db.CachePath()
+	if err != nil {
+		return time.Time{}, err
+	}
+	info, err := os.Stat(cachePath)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// downloadAndExtract downloads the GeoNames zip file and extracts it,
+// reporting the download progress
+func downloadAndExtract(ctx context.Context, baseURL string, dataset Dataset, targetPath string, progress func(downloaded, total int64)) error {
 	// Create cache directory
 	cacheDir := filepath.Dir(targetPath)
 	if err := os.MkdirAll(cacheDir, 0755); err != nil {
@@ -198,28 +785,77 @@ func downloadAndExtract(targetPath string) error {
 	}
 
 	// Download zip file to temporary location
-	tempZip := filepath.Join(cacheDir, "cities15000.zip")
-	if err := downloadFile(GeoNamesURL, tempZip); err != nil {
-		return fmt.Errorf("failed to download file: %w", err)
+	tempZip := filepath.Join(cacheDir, string(dataset)+".zip")
+	defer os.Remove(tempZip) // Clean up zip file after extraction or a failed download
+	if err := downloadWithRetry(ctx, dataset.URL(baseURL), tempZip, progress); err != nil {
+		return err
 	}
-	defer os.Remove(tempZip) // Clean up zip file after extraction
 
 	// Extract the txt file from zip
-	if err := extractFile(tempZip, CacheFileName, targetPath); err != nil {
+	if err := extractFile(tempZip, dataset.FileName(), targetPath); err != nil {
 		return fmt.Errorf("failed to extract file: %w", err)
 	}
 
 	return nil
 }
 
-// downloadFile downloads a file from URL to filepath
-func downloadFile(url, filepath string) error {
-	resp, err := http.Get(url)
+// downloadWithRetry downloads a file like downloadFile, retrying failed
+// attempts with exponential backoff until ctx is cancelled
+func downloadWithRetry(ctx context.Context, url, filepath string, progress func(downloaded, total int64)) error {
+	client := newDownloadClient()
+	backoff := downloadBackoff
+	var err error
+	for attempt := 1; attempt <= MaxDownloadAttempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+		if err = downloadFile(ctx, client, url, filepath, progress); err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	return fmt.Errorf("gave up after %d attempts: %w", MaxDownloadAttempts, err)
+}
+
+// newDownloadClient returns the HTTP client for downloads. It has no overall
+// timeout, which would cut off slow downloads, only one for the response
+func newDownloadClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = DownloadTimeout
+	return &http.Client{Transport: transport}
+}
+
+// downloadFile downloads a file from URL to filepath, calling progress as
+// data arrives. The total is -1 if the server doesn't send the size
+// Cancelling ctx aborts the request, as does no data arriving for
+// DownloadTimeout
+func downloadFile(ctx context.Context, client *http.Client, url, filepath string, progress func(downloaded, total int64)) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
+	// The stall timer restarts on every read, so only a connection that stops
+	// sending data is given up on
+	stall := time.AfterFunc(DownloadTimeout, func() { cancel(errDownloadStalled) })
+	defer stall.Stop()
+	body := &stallReader{r: resp.Body, stall: stall}
+
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("bad status: %s", resp.Status)
 	}
@@ -230,10 +866,43 @@ func downloadFile(url, filepath string) error {
 	}
 	defer out.Close()
 
-	_, err = io.Copy(out, resp.Body)
+	pw := &progressWriter{total: resp.ContentLength, progress: progress}
+	_, err = io.Copy(out, io.TeeReader(body, pw))
+	if cause := context.Cause(ctx); errors.Is(cause, errDownloadStalled) {
+		return fmt.Errorf("%w: no data for %s", cause, DownloadTimeout)
+	}
 	return err
 }
 
+// stallReader restarts the stall timer whenever data is read
+type stallReader struct {
+	r     io.Reader
+	stall *time.Timer
+}
+
+// Read reads from the underlying reader, restarting the timer on progress
+func (s *stallReader) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	if n > 0 {
+		s.stall.Reset(DownloadTimeout)
+	}
+	return n, err
+}
+
+// progressWriter counts the bytes written to it and reports the running total
+type progressWriter struct {
+	written  int64
+	total    int64
+	progress func(downloaded, total int64)
+}
+
+// Write counts p without storing it
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.written += int64(len(p))
+	w.progress(w.written, w.total)
+	return len(p), nil
+}
+
 // extractFile extracts a specific file from a zip archive
 func extractFile(zipPath, fileName, targetPath string) error {
 	r, err := zip.OpenReader(zipPath)
@@ -264,6 +933,49 @@ func extractFile(zipPath, fileName, targetPath string) error {
 	return fmt.Errorf("file %s not found in zip archive", fileName)
 }
 
+// verifyCitiesFile checks that a downloaded cities file is not empty and that
+// its first line has the columns of the GeoNames dump
+// The dump changes daily, so there is no fixed checksum to compare against
+func verifyCitiesFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	firstLine, err := bufio.NewReader(file).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	firstLine = strings.TrimRight(firstLine, "\r\n")
+	if firstLine == "" {
+		return fmt.Errorf("%s is empty", path)
+	}
+	columns, err := countColumns(firstLine)
+	if err != nil {
+		return fmt.Errorf("%s has an unreadable first line: %w", path, err)
+	}
+	if columns != geoNamesColumns {
+		return fmt.Errorf("%s has %d columns, expected %d", path, columns, geoNamesColumns)
+	}
+	return nil
+}
+
+// countColumns returns the number of fields in a GeoNames line, using the same
+// delimiter detection as parseCities so quoted comma-separated fields count once
+func countColumns(line string) (int, error) {
+	if detectDelimiter(line) == '\t' {
+		return len(strings.Split(line, "\t")), nil
+	}
+	reader := csv.NewReader(strings.NewReader(line))
+	reader.LazyQuotes = true
+	fields, err := reader.Read()
+	if err != nil {
+		return 0, err
+	}
+	return len(fields), nil
+}
+
 // parseFile parses the GeoNames cities15000.txt file
 func parseFile(path string) ([]City, error) {
 	file, err := os.Open(path)
@@ -272,49 +984,128 @@ func parseFile(path string) ([]City, error) {
 	}
 	defer file.Close()
 
+	return parseCities(file)
+}
+
+// parseCities parses GeoNames city records from r
+// The delimiter is detected from the first line: tab-separated (the format of
+// the official dump) is the default, comma-separated mirrors are also accepted
+func parseCities(r io.Reader) ([]City, error) {
+	br := bufio.NewReader(r)
+
+	// Read the first line to detect the delimiter, then replay it
+	firstLine, err := br.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	input := io.MultiReader(strings.NewReader(firstLine), br)
+
+	if detectDelimiter(firstLine) == ',' {
+		return parseCSV(input)
+	}
+	return parseTSV(input)
+}
+
+// detectDelimiter returns the field delimiter used by a GeoNames line
+func detectDelimiter(line string) rune {
+	if strings.Contains(line, "\t") {
+		return '\t'
+	}
+	if strings.Contains(line, ",") {
+		return ','
+	}
+	return '\t'
+}
+
+// parseTSV parses tab-separated GeoNames records
+func parseTSV(r io.Reader) ([]City, error) {
 	var cities []City
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(r)
 
 	// Increase buffer size for long lines
 	buf := make([]byte, 0, 64*1024)
 	scanner.Buffer(buf, 1024*1024)
 
 	for scanner.Scan() {
-		line := scanner.Text()
-		fields := strings.Split(line, "\t")
-
-		// We need at least 18 fields (timezone is at index 17)
-		if len(fields) < 18 {
-			continue
+		fields := strings.Split(scanner.Text(), "\t")
+		if city, ok := parseFields(fields); ok {
+			cities = append(cities, city)
 		}
+	}
 
-		name := fields[1]           // City name
-		countryCode := fields[8]    // Country code
-		timezone := fields[17]      // Timezone
-		populationStr := fields[14] // Population
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
 
-		// Skip if timezone is empty
-		if timezone == "" {
-			continue
-		}
+	return cities, nil
+}
 
-		// Parse population (default to 0 if parsing fails)
-		population := 0
-		if pop, err := strconv.Atoi(populationStr); err == nil {
-			population = pop
+// parseCSV parses comma-separated GeoNames records
+// Fields containing commas (e.g. alternate names) must be quoted
+func parseCSV(r io.Reader) ([]City, error) {
+	var cities []City
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1 // Rows with too few fields are skipped below
+	reader.LazyQuotes = true
+	reader.ReuseRecord = true
+
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if city, ok := parseFields(fields); ok {
+			cities = append(cities, city)
 		}
+	}
+
+	return cities, nil
+}
 
-		cities = append(cities, City{
-			Name:        name,
-			CountryCode: countryCode,
-			Timezone:    timezone,
-			Population:  population,
-		})
+// parseFields converts the fields of a single GeoNames record into a City
+// Returns false if the record should be skipped
+func parseFields(fields []string) (City, bool) {
+	// We need at least 18 fields (timezone is at index 17)
+	if len(fields) < 18 {
+		return City{}, false
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, err
+	name := fields[1]           // City name
+	asciiName := fields[2]      // City name in ASCII letters
+	alternateNames := fields[3] // Other names, comma-separated
+	countryCode := fields[8]    // Country code
+	admin1 := fields[10]        // First-level administrative division
+	timezone := fields[17]      // Timezone
+	populationStr := fields[14] // Population
+
+	// Skip if timezone is empty
+	if timezone == "" {
+		return City{}, false
 	}
 
-	return cities, nil
+	// Parse population (default to 0 if parsing fails)
+	population := 0
+	if pop, err := strconv.Atoi(populationStr); err == nil {
+		population = pop
+	}
+
+	// Parse coordinates (default to 0 if parsing fails)
+	latitude, _ := strconv.ParseFloat(fields[4], 64)
+	longitude, _ := strconv.ParseFloat(fields[5], 64)
+
+	return City{
+		Name:        name,
+		ASCIIName:   asciiName,
+		CountryCode: countryCode,
+		Admin1:      admin1,
+		Timezone:    timezone,
+		Population:  population,
+		Latitude:    latitude,
+		Longitude:   longitude,
+
+		alternateNames: alternateNames,
+	}, true
 }
@@ -0,0 +1,433 @@
+package geonames
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testCities holds a few GeoNames rows, in the columns of cities15000.txt
+var testCities = strings.Join([]string{
+	"2925533\tFrankfurt am Main\tFrankfurt am Main\tFrankfurt\t50.11552\t8.68417\tP\tPPLA2\tDE\t\t05\t064\t06412\t06412000\t763380\t\t112\tEurope/Berlin\t2024-01-01",
+	"5391959\tSan Francisco\tSan Francisco\tSF\t37.77493\t-122.41942\tP\tPPLA2\tUS\t\tCA\t075\t\t\t864816\t16\t28\tAmerica/Los_Angeles\t2024-01-01",
+	"2633352\tYork\tYork\t\t53.95763\t-1.08271\tP\tPPLA2\tGB\t\tENG\tE6\t\t\t153717\t\t17\tEurope/London\t2024-01-01",
+	"5128581\tNew York City\tNew York City\tNew York,NYC\t40.71427\t-74.00597\tP\tPPL\tUS\t\tNY\t\t\t\t8804190\t10\t57\tAmerica/New_York\t2024-01-01",
+}, "\n") + "\n"
+
+// testCSVCities holds GeoNames rows as a comma-separated mirror offers them,
+// with the comma in the alternate names quoted
+var testCSVCities = strings.Join([]string{
+	`2633352,York,York,,53.95763,-1.08271,P,PPLA2,GB,,ENG,E6,,,153717,,17,Europe/London,2024-01-01`,
+	`5128581,New York City,New York City,"New York,NYC",40.71427,-74.00597,P,PPL,US,,NY,,,,8804190,10,57,America/New_York,2024-01-01`,
+}, "\n") + "\n"
+
+func TestSearchFindsNamesContainingQuery(t *testing.T) {
+	db, err := NewDatabaseFromReader(strings.NewReader(testCities))
+	if err != nil {
+		t.Fatalf("NewDatabaseFromReader() error = %v", err)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		// Frankfurt shares the query's first letters but doesn't match
+		{"francisco", []string{"San Francisco"}},
+		// York matches exactly, New York City only contains the query
+		{"york", []string{"York", "New York City"}},
+		// A typo of the alternate name of Frankfurt am Main
+		{"Frankfrt", []string{"Frankfurt am Main"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			results := db.Search(tt.query, 10)
+			var got []string
+			for _, city := range results {
+				got = append(got, city.Name)
+			}
+			for _, name := range tt.want {
+				if !slices.Contains(got, name) {
+					t.Errorf("Search(%q) = %v, want %q among the results", tt.query, got, name)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchByCountryWithAccents(t *testing.T) {
+	saintDenis := "935264\tSaint-Denis\tSaint-Denis\t\t-20.88231\t55.4504\tP\tPPLC\tRE\t\t00\t974\t97411\t\t147931\t\t41\tIndian/Reunion\t2024-01-01\n"
+	db, err := NewDatabaseFromReader(strings.NewReader(testCities + saintDenis))
+	if err != nil {
+		t.Fatalf("NewDatabaseFromReader() error = %v", err)
+	}
+
+	// Réunion is found typed with and without its accent
+	for _, query := range []string{"reunion", "Réunion", "saint, reunion", "saint, Réu"} {
+		results := db.Search(query, 10)
+		if len(results) != 1 || results[0].Name != "Saint-Denis" {
+			t.Errorf("Search(%q) = %v, want Saint-Denis", query, results)
+		}
+	}
+}
+
+func TestParseCities(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"tab-separated", testCities, []string{
+			"Frankfurt am Main Europe/Berlin", "San Francisco America/Los_Angeles",
+			"York Europe/London", "New York City America/New_York",
+		}},
+		// The quoted comma doesn't shift the timezone column
+		{"comma-separated", testCSVCities, []string{"York Europe/London", "New York City America/New_York"}},
+		// The short row is skipped
+		{"comma-separated with short row", "1,Nowhere,Nowhere\n" + testCSVCities, []string{"York Europe/London", "New York City America/New_York"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cities, err := parseCities(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("parseCities() error = %v", err)
+			}
+			var got []string
+			for _, city := range cities {
+				got = append(got, city.Name+" "+city.Timezone)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("parseCities() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectDelimiter(t *testing.T) {
+	tests := []struct {
+		line string
+		want rune
+	}{
+		{"1\tYork\tYork\tYork,Eboracum", '\t'},
+		{`1,York,York,"York,Eboracum"`, ','},
+		{"York", '\t'},
+	}
+	for _, tt := range tests {
+		if got := detectDelimiter(tt.line); got != tt.want {
+			t.Errorf("detectDelimiter(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+// serveDataset starts a server offering the test cities as the default
+// dataset's zip file, and returns its base URL
+func serveDataset(t *testing.T) string {
+	t.Helper()
+	return serveData(t, testCities)
+}
+
+// serveData starts a server offering data as the default dataset's zip file,
+// and returns its base URL
+func serveData(t *testing.T, data string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create(DefaultDataset.FileName())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(buf.Bytes())
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL + "/"
+}
+
+// waitLoaded waits for a background download and load to finish
+func waitLoaded(t *testing.T, db *Database) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !db.IsReady() || db.IsDownloading() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the city data to load")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSearchWhileLoading(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	db := NewDatabase(DefaultDataset, serveDataset(t))
+	defer db.Close()
+
+	// Search from several goroutines while the data is downloaded, loaded,
+	// and refreshed, which the race detector checks
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				db.Search("york", 10)
+				db.IsReady()
+				db.Progress()
+			}
+		}()
+	}
+
+	db.LoadAsync()
+	waitLoaded(t, db)
+	if err := db.Refresh(); err != nil {
+		t.Errorf("Refresh() error = %v", err)
+	}
+	waitLoaded(t, db)
+	close(done)
+	wg.Wait()
+
+	if err := db.GetError(); err != nil {
+		t.Fatalf("GetError() = %v", err)
+	}
+	if got := db.Search("york", 10); len(got) != 2 {
+		t.Errorf("Search(%q) found %d cities after loading, want 2", "york", len(got))
+	}
+}
+
+// setDownloadTimeout changes DownloadTimeout until the test ends
+func setDownloadTimeout(t *testing.T, timeout time.Duration) {
+	t.Helper()
+	original := DownloadTimeout
+	DownloadTimeout = timeout
+	t.Cleanup(func() { DownloadTimeout = original })
+}
+
+func TestDownloadFileTimeouts(t *testing.T) {
+	setDownloadTimeout(t, 200*time.Millisecond)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		wantErr error
+	}{
+		{
+			// Takes several times the timeout in all, but data keeps coming
+			name: "slow",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				for range 10 {
+					w.Write([]byte("chunk\n"))
+					w.(http.Flusher).Flush()
+					time.Sleep(50 * time.Millisecond)
+				}
+			},
+		},
+		{
+			name: "stalled",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("chunk\n"))
+				w.(http.Flusher).Flush()
+				<-r.Context().Done()
+			},
+			wantErr: errDownloadStalled,
+		},
+		{
+			name: "no response",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				<-r.Context().Done()
+			},
+			wantErr: context.DeadlineExceeded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(tt.handler)
+			defer srv.Close()
+
+			path := filepath.Join(t.TempDir(), "download")
+			start := time.Now()
+			err := downloadFile(context.Background(), newDownloadClient(), srv.URL, path, func(downloaded, total int64) {})
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("downloadFile() error = %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("downloadFile() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil && time.Since(start) > 2*time.Second {
+				t.Errorf("downloadFile() took %s to give up", time.Since(start))
+			}
+		})
+	}
+}
+
+func TestFailedRefreshKeepsCache(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	db := NewDatabase(DefaultDataset, serveData(t, "not a GeoNames file\n"))
+	defer db.Close()
+
+	cachePath, err := db.CachePath()
+	if err != nil {
+		t.Fatalf("CachePath() error = %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cachePath, []byte(testCities), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.LoadSync(); err != nil {
+		t.Fatalf("LoadSync() error = %v", err)
+	}
+
+	if err := db.Refresh(); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	waitLoaded(t, db)
+	if db.GetError() == nil {
+		t.Fatal("refreshing with invalid data didn't fail")
+	}
+
+	data, err := os.ReadFile(cachePath)
+	if err != nil {
+		t.Fatalf("cached file is gone after the failed refresh: %v", err)
+	}
+	if string(data) != testCities {
+		t.Error("cached file changed after the failed refresh")
+	}
+	if got := db.Search("york", 10); len(got) != 2 {
+		t.Errorf("Search(%q) found %d cities after the failed refresh, want 2", "york", len(got))
+	}
+	entries, _ := os.ReadDir(filepath.Dir(cachePath))
+	if len(entries) != 1 {
+		t.Errorf("cache directory has %d files after the failed refresh, want only the cache", len(entries))
+	}
+}
+
+func TestDownloadCSVDataset(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	db := NewDatabase(DefaultDataset, serveData(t, testCSVCities))
+	defer db.Close()
+
+	if err := db.LoadSync(); err != nil {
+		t.Fatalf("LoadSync() error = %v", err)
+	}
+	if got := db.Search("york", 10); len(got) != 2 {
+		t.Errorf("Search(%q) found %d cities in the CSV download, want 2", "york", len(got))
+	}
+}
+
+func TestRefreshOffline(t *testing.T) {
+	db, err := NewEmbeddedDatabase()
+	if err != nil {
+		t.Fatalf("NewEmbeddedDatabase() error = %v", err)
+	}
+	defer db.Close()
+
+	if err := db.Refresh(); err == nil {
+		t.Error("Refresh() of the bundled capitals didn't fail")
+	}
+	if db.IsDownloading() {
+		t.Error("offline database is downloading")
+	}
+}
+
+func TestRefreshWhileLoadingCache(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	// The refresh download hangs until the test ends
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+	db := NewDatabase(DefaultDataset, srv.URL+"/")
+	defer db.Close()
+
+	cachePath, err := db.CachePath()
+	if err != nil {
+		t.Fatalf("CachePath() error = %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cachePath, []byte(testCities), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Refresh(); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	// Loading the cache finishes first, the refresh keeps downloading
+	if err := db.LoadSync(); err != nil {
+		t.Fatalf("LoadSync() error = %v", err)
+	}
+	if !db.IsDownloading() {
+		t.Error("loading the cache ended the refresh download")
+	}
+	if err := db.Refresh(); err == nil {
+		t.Error("second Refresh() during the download didn't fail")
+	}
+}
+
+// BenchmarkSearch searches the full dataset, when it has been downloaded to
+// the cache, and the bundled capitals. Prefix queries are answered from the
+// prefix index, substring queries also scan every name
+func BenchmarkSearch(b *testing.B) {
+	databases := []struct {
+		name string
+		load func() (*Database, error)
+	}{
+		{"full", func() (*Database, error) {
+			db := NewDatabase(DefaultDataset, "")
+			cachePath, err := db.CachePath()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := os.Stat(cachePath); err != nil {
+				return nil, err
+			}
+			return db, db.LoadSync()
+		}},
+		{"capitals", NewEmbeddedDatabase},
+	}
+	queries := []struct {
+		name  string
+		query string
+	}{
+		{"prefix", "san"},
+		{"substring", "ville"},
+	}
+
+	for _, d := range databases {
+		b.Run(d.name, func(b *testing.B) {
+			db, err := d.load()
+			if err != nil {
+				b.Skipf("dataset not available: %v", err)
+			}
+			for _, q := range queries {
+				b.Run(q.name, func(b *testing.B) {
+					for b.Loop() {
+						db.Search(q.query, 10)
+					}
+				})
+			}
+		})
+	}
+}
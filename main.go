@@ -1,15 +1,25 @@
 package main
 
 import (
+	"bufio"
+	"errors"
+	"flag"
 	"fmt"
+	"maps"
 	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
 	"github.com/philtim/worldclock/clock"
 	"github.com/philtim/worldclock/config"
 	"github.com/philtim/worldclock/geonames"
@@ -23,10 +33,33 @@ const (
 	viewAdd
 	viewDelete
 	viewConfirm
+	viewJump
+	viewSuggest
+	viewBrowse
+	viewReorder
+	viewEdit
+	viewFilter
+	viewPlanner
+	viewConvert
+	viewHelp
+)
+
+// Inputs of the edit view, in focus order
+const (
+	editName = iota
+	editLabel
+	editTimezone
 )
 
 const (
 	minClockContentWidth = 20 // Minimum content width for clock cards
+	maxAddResults        = 10 // Maximum number of search results shown in add view
+	compactAddViewHeight = 16 // Terminal height below which the add view drops title padding
+	maxSuggestions       = 5  // Maximum number of nearby cities suggested after adding
+	minTerminalWidth     = 30 // Narrower terminals show a "too small" notice instead of the UI
+	minTerminalHeight    = 5  // Shorter terminals show a "too small" notice instead of the UI
+
+	searchDebounce = 150 * time.Millisecond // Pause in typing before the add view searches
 )
 
 // tickMsg is sent every second to update the clocks
@@ -41,11 +74,27 @@ type geonamesReadyMsg struct{}
 // geonamesErrorMsg is sent when GeoNames fails to load
 type geonamesErrorMsg struct{ err error }
 
+// searchDebounceMsg is sent when typing in the add view may have paused
+// seq identifies the keystroke, newer keystrokes make it stale
+type searchDebounceMsg struct{ seq int }
+
+// progressMsg is sent while the GeoNames data downloads
+// progress is the downloaded fraction, or -1 if the size is unknown
+type progressMsg struct {
+	downloaded int64
+	progress   float64
+}
+
 // model represents the application state
+// It is only read and modified by Update and View on the Bubble Tea event
+// loop. Background work (the GeoNames download) reports back through messages
+// or state guarded by its own mutex, never by touching the model
 type model struct {
 	// Core data
 	cfg        *config.Config
 	clocks     []*clock.Clock
+	cityClocks []*clock.Clock // The same clocks in the order of cfg.Cities
+	localClock *clock.Clock   // Clock for the system timezone
 	geonamesDB *geonames.Database
 
 	// View state
@@ -56,25 +105,92 @@ type model struct {
 	width    int
 	height   int
 	quitting bool
+	notice   string // Warning shown in the command bar until the next key press
+	ascii    bool   // Use ASCII-only decorations for limited terminals
+
+	// Fixed size from --width/--height, 0 to follow the terminal
+	fixedWidth  int
+	fixedHeight int
+
+	// Config watch state
+	configModTime time.Time // Modification time of the config file when last loaded
+	asciiForced   bool      // ASCII mode was set by --ascii and ignores config changes
+
+	// Main grid state
+	cursor   int  // Index of the selected clock in clocks
+	sortDesc bool // Sort east to west, initialized from config and toggled at runtime
 
 	// Spinner state
-	spinnerFrame  int
-	geonamesReady bool
+	spinnerFrame       int
+	geonamesReady      bool
+	geonamesDownloaded int64   // Bytes of the GeoNames data downloaded so far
+	geonamesProgress   float64 // Downloaded fraction, -1 if the size is unknown
 
 	// Add mode state
 	searchInput        textinput.Model
 	searchResults      []geonames.City
+	searchSeq          int // Incremented on every change to the search input
 	selectedResult     int
-	justEnteredAddMode bool // Flag to prevent initial key from appearing in input
+	justEnteredAddMode bool      // Flag to prevent initial key from appearing in input
+	staleNotice        string    // Warning about old city data, shown in the add view
+	cacheUpdated       time.Time // When the city data was downloaded, zero if unknown
+	staleChecked       bool      // Whether the city data age was already checked this session
+
+	// Browse mode state
+	browsePrefix  string   // Timezone prefix of the current level, e.g. "America/"
+	browseEntries []string // Entries below browsePrefix, branches end in "/"
+	browseCursor  int
+
+	// Suggest mode state
+	suggestions     []geonames.City
+	suggestSelected map[int]bool
+	suggestCursor   int
 
 	// Delete mode state
-	deleteList     []string // List of city names
-	deleteSelected map[int]bool
-	deleteCursor   int
+	deleteList     []config.City   // Cities offered for deletion
+	deleteSelected map[int]bool    // Selected indices in deleteList, kept while filtering
+	deleteCursor   int             // Index in the filtered list
+	deleteInput    textinput.Model // Filters deleteList by name
+
+	// Reorder mode state
+	reorderList   []config.City // Cities in their new order
+	reorderCursor int           // Index of the selected city, which shift+up/down move
+
+	// Edit mode state
+	editCity     config.City       // The city as it was before editing
+	editIndex    int               // Index of editCity in the config
+	editInputs   []textinput.Model // Name, label, and timezone, indexed by editName etc.
+	editFocus    int               // Index of the focused input
+	editResults  []geonames.City   // Search results for the timezone input
+	editSelected int               // Index of the highlighted search result
+	editPicked   *geonames.City    // Search result whose timezone was taken, for its coordinates
+	editErr      string            // Why the changes couldn't be saved
 
 	// Confirm mode state
 	confirmMsg    string
-	confirmAction func() error
+	confirmAction func(cfg *config.Config) error // Receives the current config, which may have been reloaded meanwhile
+
+	// Jump mode state
+	jumpInput   textinput.Model
+	jumpOrigin  int  // Cursor position when the search started
+	jumpNoMatch bool // Whether the current query matches no clock
+
+	// Planner mode state
+	plannerRef  int       // Index in clocks of the reference clock
+	plannerTime time.Time // The compared moment, on a full hour of the reference clock
+
+	// Convert mode state
+	convertInput textinput.Model // Time of day in the home timezone
+
+	// Frozen time, shown instead of the current time for planning
+	frozen time.Time // Instant the clocks are frozen at, zero while they run
+
+	// Instant of the last tick, which the clocks are sorted and rendered at
+	now time.Time
+
+	// Filter mode state
+	filterInput textinput.Model
+	filter      string // Only clocks whose name contains this are shown, "" shows all
 }
 
 // Init initializes the model
@@ -82,7 +198,7 @@ func (m model) Init() tea.Cmd {
 	return tea.Batch(
 		tickCmd(),
 		spinnerTickCmd(),
-		checkGeoNamesCmd(m.geonamesDB),
+		checkGeoNamesCmd(m.geonamesDB, 0),
 	)
 }
 
@@ -101,23 +217,41 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
+		// --width/--height pin the layout regardless of the terminal size
+		if m.fixedWidth > 0 {
+			m.width = m.fixedWidth
+		}
+		if m.fixedHeight > 0 {
+			m.height = m.fixedHeight
+		}
 
 		if !m.ready {
 			// Initialize viewport
-			m.viewport = viewport.New(msg.Width, msg.Height-2) // Reserve space for command bar (1 newline + 1 bar line)
+			m.viewport = viewport.New(m.viewportWidth(), m.viewportHeight())
 			m.viewport.YPosition = 0
+			m.viewport.KeyMap = viewportKeyMap()
 			m.ready = true
 		} else {
-			m.viewport.Width = msg.Width
-			m.viewport.Height = msg.Height - 2
+			m.viewport.Width = m.viewportWidth()
+			m.viewport.Height = m.viewportHeight()
 		}
 
 	case tickMsg:
 		cmds = append(cmds, tickCmd())
+		m.updateNow()
+		if cmd := m.watchConfig(); cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+
+	case tea.FocusMsg:
+		// The terminal regained focus: redraw the clocks at the current time
+		// right away. The tick loop keeps running, so no extra tick is
+		// scheduled (that would start a second loop)
+		m.updateNow()
 
 	case spinnerTickMsg:
 		// Update spinner animation
-		m.spinnerFrame = (m.spinnerFrame + 1) % len(spinnerFrames)
+		m.spinnerFrame = (m.spinnerFrame + 1) % len(m.glyphs().spinner)
 		// Continue spinner animation only if GeoNames is not ready
 		if !m.geonamesReady {
 			cmds = append(cmds, spinnerTickCmd())
@@ -126,10 +260,34 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case geonamesReadyMsg:
 		// GeoNames database is ready
 		m.geonamesReady = true
+		m.cacheUpdated, _ = m.geonamesDB.CacheModTime()
+
+	case searchDebounceMsg:
+		// Only the last keystroke's search runs
+		if msg.seq == m.searchSeq && m.state == viewAdd && m.geonamesDB.IsReady() {
+			m.searchResults = m.searchCities(m.searchInput.Value())
+			if m.selectedResult >= len(m.searchResults) {
+				m.selectedResult = 0
+			}
+		}
+		if msg.seq == m.searchSeq && m.state == viewEdit && m.geonamesDB.IsReady() {
+			m.editResults = m.searchCities(m.editInputs[editTimezone].Value())
+			m.editSelected = 0
+		}
+
+	case progressMsg:
+		m.geonamesDownloaded = msg.downloaded
+		m.geonamesProgress = msg.progress
+		cmds = append(cmds, checkGeoNamesCmd(m.geonamesDB, msg.downloaded))
 
 	case geonamesErrorMsg:
-		m.err = msg.err
+		// With the bundled capitals loaded the error is shown in the add
+		// view instead, cities can still be added
+		if !m.geonamesDB.IsReady() {
+			m.err = msg.err
+		}
 		m.geonamesReady = true // Stop spinner on error too
+		m.cacheUpdated, _ = m.geonamesDB.CacheModTime()
 
 	case error:
 		m.err = msg
@@ -142,16 +300,15 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Only update searchInput if we didn't just enter add mode
 		// (prevents the 'a' key from appearing in the input field)
 		if !m.justEnteredAddMode {
+			query := m.searchInput.Value()
 			m.searchInput, cmd = m.searchInput.Update(msg)
 			if cmd != nil {
 				cmds = append(cmds, cmd)
 			}
-			// Update search results when input changes
-			if m.geonamesDB.IsReady() {
-				m.searchResults = m.geonamesDB.Search(m.searchInput.Value(), 50)
-				if m.selectedResult >= len(m.searchResults) {
-					m.selectedResult = 0
-				}
+			// Search once typing pauses instead of on every keystroke
+			if m.searchInput.Value() != query {
+				m.searchSeq++
+				cmds = append(cmds, searchDebounceCmd(m.searchSeq))
 			}
 		} else {
 			// Reset the flag after first update cycle
@@ -159,7 +316,11 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 	}
 
-	// Update viewport
+	// Update viewport, re-rendering the clocks only when they can have changed
+	switch msg.(type) {
+	case tea.KeyMsg, tea.WindowSizeMsg, tickMsg, tea.FocusMsg:
+		m.syncViewport()
+	}
 	m.viewport, cmd = m.viewport.Update(msg)
 	if cmd != nil {
 		cmds = append(cmds, cmd)
@@ -179,17 +340,97 @@ func (m *model) handleKeyPress(msg tea.KeyMsg) tea.Cmd {
 		return m.handleDeleteKeys(msg)
 	case viewConfirm:
 		return m.handleConfirmKeys(msg)
+	case viewJump:
+		return m.handleJumpKeys(msg)
+	case viewSuggest:
+		return m.handleSuggestKeys(msg)
+	case viewBrowse:
+		return m.handleBrowseKeys(msg)
+	case viewReorder:
+		return m.handleReorderKeys(msg)
+	case viewEdit:
+		return m.handleEditKeys(msg)
+	case viewFilter:
+		return m.handleFilterKeys(msg)
+	case viewPlanner:
+		return m.handlePlannerKeys(msg)
+	case viewConvert:
+		return m.handleConvertKeys(msg)
+	case viewHelp:
+		// Any key returns to the clocks
+		m.state = viewMain
 	}
 	return nil
 }
 
+// toggleSetting flips a display setting and saves the config, turning the
+// setting back with a notice if the config can't be saved
+func (m *model) toggleSetting(setting *bool) {
+	*setting = !*setting
+	if err := m.cfg.Save(); err != nil {
+		*setting = !*setting
+		m.notice = fmt.Sprintf("Setting not saved: %v", err)
+	}
+}
+
 // handleMainKeys handles keys in main view
 func (m *model) handleMainKeys(msg tea.KeyMsg) tea.Cmd {
+	m.notice = ""
+
 	switch msg.String() {
 	case "ctrl+c", "q":
 		m.quitting = true
 		return tea.Quit
 
+	case "left":
+		m.moveCursor(-1)
+
+	case "right":
+		m.moveCursor(1)
+
+	case "up":
+		// At the first or last row, scroll the lines beyond it into view
+		if !m.moveCursor(-m.gridColumns(m.visibleClocks(), m.width)) {
+			m.viewport.ScrollUp(1)
+		}
+
+	case "down":
+		if !m.moveCursor(m.gridColumns(m.visibleClocks(), m.width)) {
+			m.viewport.ScrollDown(1)
+		}
+
+	case "/":
+		// Filter the clocks, continuing with the current filter
+		m.state = viewFilter
+		m.filterInput.SetValue(m.filter)
+		m.filterInput.CursorEnd()
+		m.filterInput.Focus()
+		return textinput.Blink
+
+	case "esc":
+		// Show all clocks again
+		if m.filter != "" {
+			m.filterInput.Reset()
+			m.applyFilter()
+		}
+
+	case "<":
+		return m.moveClock(-1)
+
+	case ">":
+		return m.moveClock(1)
+
+	case "f":
+		// Enter jump-to-city search
+		if len(m.clocks) > 0 {
+			m.state = viewJump
+			m.jumpInput.Reset()
+			m.jumpOrigin = m.cursor
+			m.jumpNoMatch = false
+			m.jumpInput.Focus()
+			return textinput.Blink
+		}
+
 	case "a":
 		// Enter add mode
 		if m.geonamesDB.IsReady() {
@@ -198,19 +439,108 @@ func (m *model) handleMainKeys(msg tea.KeyMsg) tea.Cmd {
 			m.searchResults = []geonames.City{}
 			m.selectedResult = 0
 			m.justEnteredAddMode = true // Prevent 'a' key from appearing in input
+			m.cacheUpdated, _ = m.geonamesDB.CacheModTime()
+			// Warn about old city data once per session
+			m.staleNotice = ""
+			if !m.staleChecked {
+				m.staleChecked = true
+				m.staleNotice = staleDataNotice(m.geonamesDB, m.cfg.StaleDataAge(), clock.Now())
+			}
 			m.searchInput.Focus()
 			return textinput.Blink
 		}
 
+	case "t":
+		// Toggle card titles between city name and IANA timezone
+		m.toggleSetting(&m.cfg.ShowTimezone)
+
+	case "O":
+		// Switch to the next sort mode, keeping the selection
+		m.cycleSort()
+
+	case "o":
+		// Reverse the sort direction for this session, keeping the selection
+		m.sortDesc = !m.sortDesc
+		m.resort()
+		m.scrollToCursor()
+
+	case "s":
+		// Toggle seconds; the tick keeps running so minutes roll over on time
+		m.toggleSetting(&m.cfg.HideSeconds)
+
+	case "u":
+		// Toggle the Unix timestamp header
+		m.toggleSetting(&m.cfg.ShowUnix)
+
+	case "R":
+		// Hidden: reset the config to defaults, keeping a backup
+		m.state = viewConfirm
+		m.confirmMsg = "Reset the config to defaults? A backup of the current file is kept. (y/n)"
+		m.confirmAction = func(*config.Config) error {
+			_, err := config.Reset()
+			return err
+		}
+
+	case "m", "r":
+		// Enter reorder mode, starting at the selected clock
+		if len(m.cfg.Cities) < 2 {
+			return nil
+		}
+		m.state = viewReorder
+		m.reorderList = m.citiesInDisplayOrder()
+		m.reorderCursor = min(m.cursor, len(m.reorderList)-1)
+
+	case "?":
+		m.state = viewHelp
+
+	case "p":
+		// Compare an hour of the selected clock across all clocks
+		if len(m.clocks) > 0 {
+			m.state = viewPlanner
+			m.plannerRef = min(m.cursor, len(m.clocks)-1)
+			m.plannerTime = m.plannerHour(m.instant())
+		}
+
+	case "F":
+		// Freeze the clocks at the current moment, or let them run again
+		if m.frozen.IsZero() {
+			m.setFrozen(m.instant().Truncate(time.Minute))
+		} else {
+			m.setFrozen(time.Time{})
+		}
+
+	case "[", "]", "{", "}":
+		// Scrub the frozen time by an hour or a quarter of an hour
+		if !m.frozen.IsZero() {
+			step := map[string]time.Duration{"[": -time.Hour, "]": time.Hour, "{": -15 * time.Minute, "}": 15 * time.Minute}[msg.String()]
+			m.setFrozen(m.frozen.Add(step))
+		}
+
+	case "c":
+		// Convert a time in the home timezone to every clock
+		if len(m.clocks) > 0 {
+			m.state = viewConvert
+			m.convertInput.Reset()
+			m.convertInput.Focus()
+			return textinput.Blink
+		}
+
+	case "e":
+		// Enter edit mode for the selected clock
+		return m.startEdit()
+
 	case "d":
-		// Enter delete mode
-		m.state = viewDelete
-		m.deleteList = []string{}
-		for _, city := range m.cfg.Cities {
-			m.deleteList = append(m.deleteList, city.Name)
+		// Enter delete mode, unless there is nothing to delete
+		if len(m.cfg.Cities) == 0 {
+			return nil
 		}
+		m.state = viewDelete
+		m.deleteList = append([]config.City(nil), m.cfg.Cities...)
 		m.deleteSelected = make(map[int]bool)
 		m.deleteCursor = 0
+		m.deleteInput.Reset()
+		m.deleteInput.Focus()
+		return textinput.Blink
 	}
 
 	return nil
@@ -224,6 +554,23 @@ func (m *model) handleAddKeys(msg tea.KeyMsg) tea.Cmd {
 		m.state = viewMain
 		return nil
 
+	case "ctrl+r":
+		// Download the city data again, the current data stays searchable
+		if err := m.geonamesDB.Refresh(); err != nil {
+			m.staleNotice = err.Error()
+			return nil
+		}
+		m.staleNotice = ""
+		m.geonamesReady = false
+		m.geonamesDownloaded = 0
+		return tea.Batch(spinnerTickCmd(), checkGeoNamesCmd(m.geonamesDB, 0))
+
+	case "tab":
+		// Browse timezones as a tree instead of searching
+		m.state = viewBrowse
+		m.browseTo("")
+		return nil
+
 	case "up":
 		if m.selectedResult > 0 {
 			m.selectedResult--
@@ -238,7 +585,10 @@ func (m *model) handleAddKeys(msg tea.KeyMsg) tea.Cmd {
 		// Add selected city
 		if len(m.searchResults) > 0 && m.selectedResult < len(m.searchResults) {
 			city := m.searchResults[m.selectedResult]
-			if err := m.cfg.AddCity(city.Name, city.Timezone); err != nil {
+			if err := m.addCity(city); errors.Is(err, config.ErrDuplicateTimezone) {
+				m.confirmDuplicate(city, err)
+				return nil
+			} else if err != nil {
 				m.err = err
 				return nil
 			}
@@ -247,19 +597,282 @@ func (m *model) handleAddKeys(msg tea.KeyMsg) tea.Cmd {
 				return nil
 			}
 			// Reload clocks
-			return m.reloadClocks()
+			cmd := m.reloadClocks()
+
+			// Offer other major cities from the same country
+			m.suggestions = nil
+			for _, suggestion := range m.geonamesDB.Suggest(city, maxSuggestions+len(m.cfg.Cities)) {
+				if !m.isConfigured(suggestion) && len(m.suggestions) < maxSuggestions {
+					m.suggestions = append(m.suggestions, suggestion)
+				}
+			}
+			if len(m.suggestions) > 0 {
+				m.state = viewSuggest
+				m.suggestSelected = make(map[int]bool)
+				m.suggestCursor = 0
+			}
+			return cmd
+		}
+	}
+
+	return nil
+}
+
+// handleSuggestKeys handles keys in suggest view
+func (m *model) handleSuggestKeys(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc":
+		// Skip suggestions and return to main
+		m.state = viewMain
+		return nil
+
+	case "up":
+		if m.suggestCursor > 0 {
+			m.suggestCursor--
+		}
+
+	case "down":
+		if m.suggestCursor < len(m.suggestions)-1 {
+			m.suggestCursor++
+		}
+
+	case " ":
+		// Toggle selection
+		m.suggestSelected[m.suggestCursor] = !m.suggestSelected[m.suggestCursor]
+
+	case "enter":
+		// Add selected cities to a copy of the config, which replaces it only
+		// once all of them are added and saved
+		cfg := *m.cfg
+		cfg.Cities = slices.Clone(m.cfg.Cities)
+		added := false
+		for idx, city := range m.suggestions {
+			if !m.suggestSelected[idx] {
+				continue
+			}
+			// Picked on purpose, cities of one country often share a timezone
+			// A limit like max_clocks leaves the config unchanged
+			if err := cfg.AddCityAnyway(cityEntry(city)); err != nil {
+				m.notice = fmt.Sprintf("Cities not added: %v", err)
+				m.state = viewMain
+				return nil
+			}
+			added = true
+		}
+		if !added {
+			m.state = viewMain
+			return nil
+		}
+		if err := cfg.Save(); err != nil {
+			m.notice = fmt.Sprintf("Cities not added: %v", err)
+			m.state = viewMain
+			return nil
+		}
+		m.cfg = &cfg
+		return m.reloadClocks()
+	}
+
+	return nil
+}
+
+// addCity adds a search result to the config, including its coordinates
+// unless it is a timezone typed directly (which has no country or position)
+func (m *model) addCity(city geonames.City) error {
+	if city.CountryCode == "" {
+		return m.cfg.AddCity(city.Name, city.Timezone)
+	}
+	return m.cfg.AddCityAt(city.Name, city.Timezone, city.Latitude, city.Longitude)
+}
+
+// cityEntry converts a search result into a config entry, like addCity
+func cityEntry(city geonames.City) config.City {
+	entry := config.City{Name: city.Name, Timezone: city.Timezone}
+	if city.CountryCode != "" {
+		latitude, longitude := city.Latitude, city.Longitude
+		entry.Latitude, entry.Longitude = &latitude, &longitude
+	}
+	return entry
+}
+
+// confirmDuplicate asks whether to add a city whose timezone another city
+// already shows
+func (m *model) confirmDuplicate(city geonames.City, err error) {
+	entry := cityEntry(city)
+	m.state = viewConfirm
+	m.confirmMsg = fmt.Sprintf("%v. Add '%s' anyway? (y/n)", err, city.Name)
+	m.confirmAction = func(cfg *config.Config) error {
+		if err := cfg.AddCityAnyway(entry); err != nil {
+			return err
+		}
+		return cfg.Save()
+	}
+}
+
+// searchCities searches the GeoNames database, offering the query itself
+// first when it is a valid IANA timezone name (e.g. "Etc/GMT+5")
+func (m *model) searchCities(query string) []geonames.City {
+	results := m.geonamesDB.Search(query, 50)
+
+	query = strings.TrimSpace(query)
+	if query == "" || query == "Local" {
+		return results
+	}
+	if _, err := time.LoadLocation(query); err != nil {
+		return results
+	}
+	name := query[strings.LastIndex(query, "/")+1:]
+	zone := geonames.City{Name: strings.ReplaceAll(name, "_", " "), Timezone: query}
+	return append([]geonames.City{zone}, results...)
+}
+
+// etcGMTPattern matches POSIX-style fixed-offset zones like "Etc/GMT+5"
+var etcGMTPattern = regexp.MustCompile(`^Etc/GMT[+-]\d+$`)
+
+// etcGMTNote returns a warning explaining the actual offset of an Etc/GMT±N
+// zone, whose sign is inverted (Etc/GMT+5 is UTC-5), or "" for other zones
+func etcGMTNote(timezone string) string {
+	if !etcGMTPattern.MatchString(timezone) {
+		return ""
+	}
+	clk, err := clock.New(timezone, timezone)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("Note: %s is %s (POSIX-style names invert the sign)", timezone, clk.FormatUTCOffset())
+}
+
+// handleBrowseKeys handles keys in browse view
+func (m *model) handleBrowseKeys(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc", "tab":
+		// Back to the search
+		m.state = viewAdd
+		return nil
+
+	case "up":
+		if m.browseCursor > 0 {
+			m.browseCursor--
+		}
+
+	case "down":
+		if m.browseCursor < len(m.browseEntries)-1 {
+			m.browseCursor++
+		}
+
+	case "left", "backspace":
+		// Go up one level, keeping the entry we came from selected
+		if m.browsePrefix == "" {
+			return nil
+		}
+		current := m.browsePrefix
+		trimmed := strings.TrimSuffix(current, "/")
+		parent := trimmed[:strings.LastIndex(trimmed, "/")+1]
+		m.browseTo(parent)
+		for i, entry := range m.browseEntries {
+			if parent+entry == current {
+				m.browseCursor = i
+			}
+		}
+
+	case "right", "enter":
+		if m.browseCursor >= len(m.browseEntries) {
+			return nil
+		}
+		entry := m.browseEntries[m.browseCursor]
+		if strings.HasSuffix(entry, "/") {
+			m.browseTo(m.browsePrefix + entry)
+			return nil
+		}
+		if msg.String() == "right" {
+			return nil
+		}
+
+		// Leaf: add the timezone as a city like the search does
+		timezone := m.browsePrefix + entry
+		city := geonames.City{Name: strings.ReplaceAll(entry, "_", " "), Timezone: timezone}
+		if err := m.addCity(city); errors.Is(err, config.ErrDuplicateTimezone) {
+			m.confirmDuplicate(city, err)
+			return nil
+		} else if err != nil {
+			m.err = err
+			return nil
 		}
+		if err := m.cfg.Save(); err != nil {
+			m.err = err
+			return nil
+		}
+		return m.reloadClocks()
 	}
 
 	return nil
 }
 
+// browseTo shows the timezone tree entries below prefix
+func (m *model) browseTo(prefix string) {
+	m.browsePrefix = prefix
+	m.browseEntries = browseEntries(m.geonamesDB.Timezones(), prefix)
+	m.browseCursor = 0
+}
+
+// browseEntries returns the distinct next path segments of the timezones
+// starting with prefix. Segments with children end in "/"
+func browseEntries(timezones []string, prefix string) []string {
+	var entries []string
+	seen := make(map[string]bool)
+	for _, tz := range timezones {
+		if !strings.HasPrefix(tz, prefix) {
+			continue
+		}
+		entry := tz[len(prefix):]
+		if i := strings.Index(entry, "/"); i >= 0 {
+			entry = entry[:i+1]
+		}
+		if entry != "" && !seen[entry] {
+			seen[entry] = true
+			entries = append(entries, entry)
+		}
+	}
+	sort.Strings(entries)
+	return entries
+}
+
+// staleDataNotice returns a warning if the cached city data is older than
+// maxAge, or "" if it's recent enough or maxAge is 0
+func staleDataNotice(db *geonames.Database, maxAge time.Duration, now time.Time) string {
+	if maxAge == 0 {
+		return ""
+	}
+	modTime, err := db.CacheModTime()
+	if err != nil || now.Sub(modTime) < maxAge {
+		return ""
+	}
+	age := fmt.Sprintf("%d days", int(now.Sub(modTime).Hours()/24))
+	if months := int(now.Sub(modTime).Hours() / 24 / 30); months > 1 {
+		age = fmt.Sprintf("%d months", months)
+	}
+	return fmt.Sprintf("City data is %s old; press Ctrl+R to download it again", age)
+}
+
+// isConfigured checks whether a GeoNames city is already in the config, with
+// the duplicate check used when adding it
+func (m *model) isConfigured(city geonames.City) bool {
+	return m.cfg.HasCity(cityEntry(city))
+}
+
 // handleDeleteKeys handles keys in delete view
 func (m *model) handleDeleteKeys(msg tea.KeyMsg) tea.Cmd {
+	matches := m.deleteMatches()
+
 	switch msg.String() {
 	case "esc":
-		// Cancel and return to main
+		// Clear the filter first, then cancel and return to main
+		if m.deleteInput.Value() != "" {
+			m.deleteInput.Reset()
+			m.deleteCursor = 0
+			return nil
+		}
 		m.state = viewMain
+		m.deleteInput.Blur()
 		return nil
 
 	case "up":
@@ -268,13 +881,16 @@ func (m *model) handleDeleteKeys(msg tea.KeyMsg) tea.Cmd {
 		}
 
 	case "down":
-		if m.deleteCursor < len(m.deleteList)-1 {
+		if m.deleteCursor < len(matches)-1 {
 			m.deleteCursor++
 		}
 
-	case " ":
-		// Toggle selection
-		m.deleteSelected[m.deleteCursor] = !m.deleteSelected[m.deleteCursor]
+	case "tab":
+		// Toggle selection, space is typed into the filter
+		if m.deleteCursor < len(matches) {
+			idx := matches[m.deleteCursor]
+			m.deleteSelected[idx] = !m.deleteSelected[idx]
+		}
 
 	case "enter":
 		// Delete selected cities
@@ -283,232 +899,1688 @@ func (m *model) handleDeleteKeys(msg tea.KeyMsg) tea.Cmd {
 			return nil
 		}
 
-		// Collect selected city names
-		var toDelete []string
+		// Collect the selected cities, deleteList holds the config's cities
+		// in order
+		var toDelete []int
+		var label string
 		for idx := range m.deleteSelected {
 			if m.deleteSelected[idx] {
-				toDelete = append(toDelete, m.deleteList[idx])
+				toDelete = append(toDelete, idx)
+				label = m.deleteList[idx].DisplayName()
 			}
 		}
 
 		// Set up confirmation
 		m.state = viewConfirm
 		if len(toDelete) == 1 {
-			m.confirmMsg = fmt.Sprintf("Delete '%s'? (y/n)", toDelete[0])
+			m.confirmMsg = fmt.Sprintf("Delete '%s'? (y/n)", label)
 		} else {
 			m.confirmMsg = fmt.Sprintf("Delete %d selected cities? (y/n)", len(toDelete))
 		}
-		m.confirmAction = func() error {
-			if err := m.cfg.DeleteCities(toDelete); err != nil {
+		deleteList := m.deleteList
+		m.confirmAction = func(cfg *config.Config) error {
+			// The config may have been reloaded meanwhile
+			for _, idx := range toDelete {
+				if idx >= len(cfg.Cities) || !cfg.Cities[idx].Same(deleteList[idx]) {
+					return fmt.Errorf("'%s' is no longer in the config", deleteList[idx].DisplayName())
+				}
+			}
+			if err := cfg.DeleteCities(toDelete); err != nil {
 				return err
 			}
-			return m.cfg.Save()
+			return cfg.Save()
 		}
+		return nil
 	}
 
-	return nil
+	// Type to filter the list, starting again at the first match
+	query := m.deleteInput.Value()
+	var cmd tea.Cmd
+	m.deleteInput, cmd = m.deleteInput.Update(msg)
+	if m.deleteInput.Value() != query {
+		m.deleteCursor = 0
+	}
+	return cmd
 }
 
-// handleConfirmKeys handles keys in confirm view
-func (m *model) handleConfirmKeys(msg tea.KeyMsg) tea.Cmd {
-	switch msg.String() {
-	case "y":
-		// Confirm action
-		if err := m.confirmAction(); err != nil {
-			m.err = err
-			m.state = viewMain
-			return nil
+// deleteMatches returns the indices in deleteList of the cities whose name
+// or label contains the delete filter, ignoring case
+func (m model) deleteMatches() []int {
+	query := strings.ToLower(strings.TrimSpace(m.deleteInput.Value()))
+	var matches []int
+	for i, city := range m.deleteList {
+		if strings.Contains(strings.ToLower(city.Name), query) || strings.Contains(strings.ToLower(city.Label), query) {
+			matches = append(matches, i)
 		}
-		// Reload clocks and return to main
-		return m.reloadClocks()
-
-	case "n", "esc":
-		// Cancel and return to main
-		m.state = viewMain
-		return nil
 	}
-
-	return nil
+	return matches
 }
 
-// reloadClocks reloads the configuration and recreates clocks
-func (m *model) reloadClocks() tea.Cmd {
-	// Reload config
-	cfg, err := config.Load()
-	if err != nil {
-		m.err = err
+// handleReorderKeys handles keys in reorder view
+func (m *model) handleReorderKeys(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc":
+		// Cancel without saving
 		m.state = viewMain
 		return nil
-	}
-	m.cfg = cfg
 
-	// Recreate clocks
-	var clocks []*clock.Clock
-	for _, city := range m.cfg.Cities {
-		clk, err := clock.New(city.Name, city.Timezone)
-		if err != nil {
+	case "up":
+		if m.reorderCursor > 0 {
+			m.reorderCursor--
+		}
+
+	case "down":
+		if m.reorderCursor < len(m.reorderList)-1 {
+			m.reorderCursor++
+		}
+
+	case "shift+up":
+		if m.reorderCursor > 0 {
+			i := m.reorderCursor
+			m.reorderList[i-1], m.reorderList[i] = m.reorderList[i], m.reorderList[i-1]
+			m.reorderCursor--
+		}
+
+	case "shift+down":
+		if m.reorderCursor < len(m.reorderList)-1 {
+			i := m.reorderCursor
+			m.reorderList[i+1], m.reorderList[i] = m.reorderList[i], m.reorderList[i+1]
+			m.reorderCursor++
+		}
+
+	case "enter":
+		// Save the new order, which only shows with manual sorting
+		m.cfg.Cities = m.reorderList
+		m.cfg.Sort = config.SortManual
+		if err := m.cfg.Save(); err != nil {
 			m.err = err
-			m.state = viewMain
 			return nil
 		}
-		clocks = append(clocks, clk)
+		cmd := m.reloadClocks()
+		m.cursor = m.reorderCursor
+		m.scrollToCursor()
+		return cmd
 	}
 
-	// Sort by UTC offset
-	clock.SortByUTCOffset(clocks)
-	m.clocks = clocks
-
-	// Return to main view
-	m.state = viewMain
 	return nil
 }
 
-// View renders the UI
-func (m model) View() string {
-	if m.err != nil {
-		return fmt.Sprintf("Error: %v\n\nPress 'q' to quit", m.err)
+// citiesInDisplayOrder returns the configured cities in the order their
+// clocks are shown
+func (m *model) citiesInDisplayOrder() []config.City {
+	used := make([]bool, len(m.cfg.Cities))
+	var cities []config.City
+	for _, clk := range m.clocks {
+		if i := m.cityIndex(clk); i >= 0 && !used[i] {
+			used[i] = true
+			cities = append(cities, m.cfg.Cities[i])
+		}
 	}
-
-	if m.quitting {
-		return "Goodbye!\n"
+	// Cities without a matching clock keep their relative order at the end
+	for i, city := range m.cfg.Cities {
+		if !used[i] {
+			cities = append(cities, city)
+		}
+	}
+	return cities
+}
+
+// startEdit enters edit mode for the city of the selected clock
+func (m *model) startEdit() tea.Cmd {
+	if m.visibleIndex() < 0 {
+		return nil
+	}
+	index := m.cityIndex(m.clocks[m.cursor])
+	if index < 0 {
+		return nil
+	}
+	city := m.cfg.Cities[index]
+
+	m.state = viewEdit
+	m.editCity = city
+	m.editIndex = index
+	m.editInputs = []textinput.Model{
+		newEditInput(city.Name),
+		newEditInput(city.Label),
+		newEditInput(city.Timezone),
+	}
+	m.editFocus = editName
+	m.editInputs[editName].Focus()
+	m.editResults = nil
+	m.editSelected = 0
+	m.editPicked = nil
+	m.editErr = ""
+	return textinput.Blink
+}
+
+// newEditInput returns an input for the edit view holding value
+func newEditInput(value string) textinput.Model {
+	ti := textinput.New()
+	ti.CharLimit = 50
+	ti.Width = 50
+	ti.SetValue(value)
+	return ti
+}
+
+// cityIndex returns the index of the configured city shown by clk, or -1.
+// Labels and names can repeat, so the clock itself is looked up
+func (m *model) cityIndex(clk *clock.Clock) int {
+	i := slices.Index(m.cityClocks, clk)
+	if i >= len(m.cfg.Cities) {
+		return -1
+	}
+	return i
+}
+
+// handleEditKeys handles keys in edit view
+func (m *model) handleEditKeys(msg tea.KeyMsg) tea.Cmd {
+	// Cities with several zones keep them, their timezone isn't editable
+	inputs := len(m.editInputs)
+	if len(m.editCity.Zones) > 0 {
+		inputs = editTimezone
+	}
+	searching := m.editFocus == editTimezone && len(m.editResults) > 0
+
+	switch msg.String() {
+	case "esc":
+		// Discard the changes
+		m.state = viewMain
+		return nil
+
+	case "tab", "down":
+		if msg.String() == "down" && searching {
+			m.editSelected = min(m.editSelected+1, len(m.editResults)-1)
+			return nil
+		}
+		return m.focusEditInput((m.editFocus + 1) % inputs)
+
+	case "shift+tab", "up":
+		if msg.String() == "up" && searching {
+			m.editSelected = max(m.editSelected-1, 0)
+			return nil
+		}
+		return m.focusEditInput((m.editFocus + inputs - 1) % inputs)
+
+	case "enter":
+		// Take the highlighted city's timezone, or save once it's chosen
+		if searching {
+			city := m.editResults[m.editSelected]
+			m.editInputs[editTimezone].SetValue(city.Timezone)
+			m.editInputs[editTimezone].CursorEnd()
+			m.editPicked = &city
+			m.editResults = nil
+			return nil
+		}
+		return m.saveEdit()
+	}
+
+	// Type into the focused input, searching cities for the timezone
+	var cmd tea.Cmd
+	value := m.editInputs[m.editFocus].Value()
+	m.editInputs[m.editFocus], cmd = m.editInputs[m.editFocus].Update(msg)
+	if m.editFocus == editTimezone && m.editInputs[editTimezone].Value() != value {
+		m.editPicked = nil
+		m.searchSeq++
+		return tea.Batch(cmd, searchDebounceCmd(m.searchSeq))
+	}
+	return cmd
+}
+
+// focusEditInput moves the focus in the edit view to the input at index
+func (m *model) focusEditInput(index int) tea.Cmd {
+	m.editInputs[m.editFocus].Blur()
+	m.editFocus = index
+	m.editResults = nil
+	return m.editInputs[index].Focus()
+}
+
+// saveEdit applies the edit view's changes to the config and saves it
+func (m *model) saveEdit() tea.Cmd {
+	// The config may have been reloaded meanwhile, check the city is still
+	// at its index. Copies of a city with other labels are other entries
+	index := m.editIndex
+	if index >= len(m.cfg.Cities) || !m.cfg.Cities[index].Same(m.editCity) {
+		m.editErr = fmt.Sprintf("'%s' is no longer in the config", m.editCity.DisplayName())
+		return nil
+	}
+
+	city := m.cfg.Cities[index]
+	city.Name = strings.TrimSpace(m.editInputs[editName].Value())
+	city.Label = strings.TrimSpace(m.editInputs[editLabel].Value())
+	if timezone := strings.TrimSpace(m.editInputs[editTimezone].Value()); len(city.Zones) == 0 && timezone != city.Timezone {
+		// The old coordinates belong to another place, take the picked
+		// city's or drop them
+		city.Timezone = timezone
+		city.Latitude, city.Longitude = nil, nil
+		if m.editPicked != nil && m.editPicked.Timezone == timezone && m.editPicked.CountryCode != "" {
+			latitude, longitude := m.editPicked.Latitude, m.editPicked.Longitude
+			city.Latitude, city.Longitude = &latitude, &longitude
+		}
+	}
+	if err := m.cfg.UpdateCity(index, city); err != nil {
+		m.editErr = err.Error()
+		return nil
+	}
+	if err := m.cfg.Save(); err != nil {
+		m.err = err
+		return nil
+	}
+	return m.reloadClocks()
+}
+
+// handleConfirmKeys handles keys in confirm view
+func (m *model) handleConfirmKeys(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "y":
+		// Confirm action
+		if err := m.confirmAction(m.cfg); err != nil {
+			m.err = err
+			m.state = viewMain
+			return nil
+		}
+		// Reload clocks and return to main
+		return m.reloadClocks()
+
+	case "n", "esc":
+		// Cancel and return to main
+		m.state = viewMain
+		return nil
+	}
+
+	return nil
+}
+
+// handleJumpKeys handles keys in jump mode
+func (m *model) handleJumpKeys(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc":
+		// Leave search, keeping the cursor on the current match
+		m.state = viewMain
+		m.jumpInput.Blur()
+		return nil
+
+	case "enter":
+		// Jump to the next match, wrapping around
+		m.jumpTo(m.cursor + 1)
+		return nil
+	}
+
+	// Incremental search from where the search started
+	var cmd tea.Cmd
+	m.jumpInput, cmd = m.jumpInput.Update(msg)
+	m.jumpTo(m.jumpOrigin)
+	return cmd
+}
+
+// handlePlannerKeys handles keys in planner view
+func (m *model) handlePlannerKeys(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc":
+		m.state = viewMain
+		return nil
+
+	case "left":
+		m.plannerTime = m.plannerTime.Add(-time.Hour)
+
+	case "right":
+		m.plannerTime = m.plannerTime.Add(time.Hour)
+
+	case "up":
+		if m.plannerRef > 0 {
+			m.plannerRef--
+			m.plannerTime = m.plannerHour(m.plannerTime)
+		}
+
+	case "down":
+		if m.plannerRef < len(m.clocks)-1 {
+			m.plannerRef++
+			m.plannerTime = m.plannerHour(m.plannerTime)
+		}
+
+	case "n":
+		m.plannerTime = m.plannerHour(m.instant())
+	}
+
+	return nil
+}
+
+// plannerHour returns t rounded down to the full hour of the reference clock,
+// which differs from UTC's for zones like UTC+05:45
+func (m *model) plannerHour(t time.Time) time.Time {
+	if m.plannerRef < len(m.clocks) {
+		t = t.In(m.clocks[m.plannerRef].Location)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+}
+
+// handleConvertKeys handles keys in convert mode
+func (m *model) handleConvertKeys(msg tea.KeyMsg) tea.Cmd {
+	if msg.String() == "esc" {
+		m.state = viewMain
+		m.convertInput.Blur()
+		return nil
+	}
+
+	var cmd tea.Cmd
+	m.convertInput, cmd = m.convertInput.Update(msg)
+	return cmd
+}
+
+// setFrozen freezes the clocks at t, or lets them run again when t is zero
+// Clocks sorted by working hours are re-sorted for the new time
+func (m *model) setFrozen(t time.Time) {
+	m.frozen = t
+	clock.Freeze(m.clocks, t)
+	if m.localClock != nil {
+		clock.Freeze([]*clock.Clock{m.localClock}, t)
+	}
+	if m.cfg.Sort == config.SortActiveFirst {
+		m.resort()
+	}
+}
+
+// convertClock returns the clock whose timezone entered times are read in:
+// the home clock, or the local timezone without one
+func (m model) convertClock() *clock.Clock {
+	if home := m.homeClock(); home != nil {
+		return home
+	}
+	return m.localClock
+}
+
+// parseTimeOfDay parses a time of day like "15:30", "15", "3pm", or
+// "3:30 PM" and returns it on the day of ref in ref's timezone
+func parseTimeOfDay(value string, ref time.Time) (time.Time, error) {
+	value = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(value), " ", ""))
+	for _, layout := range []string{"15:04", "15", "3:04PM", "3PM"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return time.Date(ref.Year(), ref.Month(), ref.Day(), t.Hour(), t.Minute(), 0, 0, ref.Location()), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("enter a time like 15:30 or 3:30pm")
+}
+
+// handleFilterKeys handles keys in filter mode
+func (m *model) handleFilterKeys(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc":
+		// Clear the filter and show all clocks
+		m.state = viewMain
+		m.filterInput.Blur()
+		m.filterInput.Reset()
+		m.applyFilter()
+		return nil
+
+	case "enter":
+		// Keep the filter while using the main view
+		m.state = viewMain
+		m.filterInput.Blur()
+		return nil
+	}
+
+	var cmd tea.Cmd
+	m.filterInput, cmd = m.filterInput.Update(msg)
+	m.applyFilter()
+	return cmd
+}
+
+// applyFilter filters the clocks by the filter input, moving the cursor to
+// the first remaining clock if the selected one was filtered out
+func (m *model) applyFilter() {
+	m.filter = strings.TrimSpace(m.filterInput.Value())
+	if m.visibleIndex() < 0 {
+		if visible := m.visibleClocks(); len(visible) > 0 {
+			m.cursor = slices.Index(m.clocks, visible[0])
+		}
+	}
+	m.scrollToCursor()
+}
+
+// visibleClocks returns the clocks shown in the grid: those whose name or
+// label contains the filter, ignoring case, or all clocks without a filter
+func (m model) visibleClocks() []*clock.Clock {
+	if m.filter == "" {
+		return m.clocks
+	}
+	query := strings.ToLower(m.filter)
+	var clocks []*clock.Clock
+	for _, clk := range m.clocks {
+		// The card shows the label, the city's name still matches
+		name := clk.Name
+		if i := m.cityIndex(clk); i >= 0 {
+			name = m.cfg.Cities[i].Name
+		}
+		if strings.Contains(strings.ToLower(clk.Name), query) || strings.Contains(strings.ToLower(name), query) {
+			clocks = append(clocks, clk)
+		}
+	}
+	return clocks
+}
+
+// visibleIndex returns the position of the selected clock in the grid, or -1
+// if it is filtered out
+func (m model) visibleIndex() int {
+	if m.cursor >= len(m.clocks) {
+		return -1
+	}
+	return slices.Index(m.visibleClocks(), m.clocks[m.cursor])
+}
+
+// jumpTo moves the cursor to the first clock matching the jump query,
+// searching from start and wrapping around
+func (m *model) jumpTo(start int) {
+	if strings.TrimSpace(m.jumpInput.Value()) == "" {
+		m.cursor = m.jumpOrigin
+		m.jumpNoMatch = false
+		m.scrollToCursor()
+		return
+	}
+
+	// Search only the clocks the filter shows, starting at the first of them
+	// at or after start
+	visible := m.visibleClocks()
+	from := 0
+	for i, clk := range visible {
+		if slices.Index(m.clocks, clk) >= start {
+			from = i
+			break
+		}
+	}
+	idx := findClock(visible, m.jumpInput.Value(), from)
+	if idx < 0 {
+		m.jumpNoMatch = true
+		return
+	}
+	m.jumpNoMatch = false
+	m.cursor = slices.Index(m.clocks, visible[idx])
+	m.scrollToCursor()
+}
+
+// moveCursor moves the grid cursor by delta cards if the target exists and
+// reports whether it moved
+func (m *model) moveCursor(delta int) bool {
+	visible := m.visibleClocks()
+	pos := m.visibleIndex()
+	next := pos + delta
+	if pos < 0 || next < 0 || next >= len(visible) {
+		return false
+	}
+	m.cursor = slices.Index(m.clocks, visible[next])
+	m.scrollToCursor()
+	return true
+}
+
+// scrollToCursor scrolls the viewport so the selected card is visible
+func (m *model) scrollToCursor() {
+	pos := m.visibleIndex()
+	if !m.ready || pos < 0 {
+		return
+	}
+	m.syncViewport()
+
+	// Find the line range of the row holding the cursor
+	cols := m.gridColumns(m.visibleClocks(), m.width)
+	rows := m.clockRows(m.width)
+	row := pos / cols
+	top := 0
+	for _, r := range rows[:row] {
+		top += lipgloss.Height(r)
+	}
+	bottom := top + lipgloss.Height(rows[row])
+
+	if top < m.viewport.YOffset {
+		m.viewport.SetYOffset(top)
+	} else if bottom > m.viewport.YOffset+m.viewport.Height {
+		m.viewport.SetYOffset(bottom - m.viewport.Height)
+	}
+}
+
+// syncViewport refreshes the viewport size and content with the current clocks
+func (m *model) syncViewport() {
+	if m.ready {
+		m.viewport.Height = m.viewportHeight()
+		m.viewport.SetContent(m.renderClocks(m.width, m.viewport.Height))
+		// A taller window or fewer clocks can leave the offset past the end
+		if m.viewport.PastBottom() {
+			m.viewport.GotoBottom()
+		}
+	}
+}
+
+// findClock returns the index of the first clock at or after start (wrapping
+// around) whose name or timezone matches query, or -1 if none matches
+// Substring matches are preferred over fuzzy matches
+func findClock(clocks []*clock.Clock, query string, start int) int {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" || len(clocks) == 0 {
+		return -1
+	}
+
+	for _, match := range []func(string, string) bool{strings.Contains, fuzzyMatch} {
+		for i := 0; i < len(clocks); i++ {
+			idx := (start + i) % len(clocks)
+			clk := clocks[idx]
+			if match(strings.ToLower(clk.Name), query) || match(strings.ToLower(clk.Location.String()), query) {
+				return idx
+			}
+		}
+	}
+	return -1
+}
+
+// fuzzyMatch reports whether the characters of query appear in text in order
+func fuzzyMatch(text, query string) bool {
+	q := []rune(query)
+	i := 0
+	for _, r := range text {
+		if i < len(q) && r == q[i] {
+			i++
+		}
+	}
+	return i == len(q)
+}
+
+// reloadClocks reloads the configuration and recreates clocks
+func (m *model) reloadClocks() tea.Cmd {
+	// Reload config
+	cfg, err := config.Load()
+	if err != nil {
+		m.err = err
+		m.state = viewMain
+		return nil
+	}
+	m.configModTime, _ = config.ModTime()
+
+	// Recreate clocks and presentation settings
+	cmd, err := m.applyConfig(cfg)
+	if err != nil {
+		m.err = err
+		m.state = viewMain
+		return nil
+	}
+
+	// Return to main view
+	m.state = viewMain
+	return cmd
+}
+
+// watchConfig reloads the configuration when the file changed on disk, so
+// edits to the YAML apply without restarting
+func (m *model) watchConfig() tea.Cmd {
+	modTime, err := config.ModTime()
+	if err != nil || modTime.Equal(m.configModTime) {
+		return nil
+	}
+	m.configModTime = modTime
+
+	cfg, err := config.Load()
+	if err != nil {
+		// Keep the current config, the file may be saved half-edited
+		m.notice = fmt.Sprintf("Config not reloaded: %v", err)
+		return nil
+	}
+	cmd, err := m.applyConfig(cfg)
+	if err != nil {
+		m.notice = fmt.Sprintf("Config not reloaded: %v", err)
+	}
+	return cmd
+}
+
+// applyConfig switches the model to cfg, rebuilding the clocks and
+// re-deriving the presentation settings read from it. Settings that can be
+// changed at runtime (sort direction, --ascii) are only overridden when the
+// config value itself changed
+func (m *model) applyConfig(cfg *config.Config) (tea.Cmd, error) {
+	sortDesc := m.sortDesc
+	if cfg.SortDesc != m.cfg.SortDesc {
+		sortDesc = cfg.SortDesc
+	}
+	cityClocks, err := buildClocks(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var cmd tea.Cmd
+	if cfg.FocusReporting() != m.cfg.FocusReporting() {
+		if cfg.FocusReporting() {
+			cmd = tea.EnableReportFocus
+		} else {
+			cmd = tea.DisableReportFocus
+		}
+	}
+	if !m.asciiForced && cfg.ASCII != m.cfg.ASCII {
+		m.ascii = cfg.ASCII || termLacksUnicode()
+		// The spinners have different frame counts
+		m.spinnerFrame = 0
+	}
+
+	m.cfg = cfg
+	m.sortDesc = sortDesc
+	m.cityClocks = cityClocks
+	m.clocks = slices.Clone(cityClocks)
+	sortClocks(m.clocks, cfg.Sort, sortDesc, m.instant())
+	clock.Freeze(m.clocks, m.frozen)
+	if m.cursor >= len(m.clocks) {
+		m.cursor = max(len(m.clocks)-1, 0)
+	}
+	if notice := oldTimezonesNotice(cfg); notice != "" {
+		m.notice = notice
+	}
+	if cfg.ExceedsMaxClocks() {
+		m.notice = maxClocksNotice(cfg)
+	}
+	return cmd, nil
+}
+
+// maxClocksNotice returns the warning shown when more cities are configured
+// than max_clocks allows
+func maxClocksNotice(cfg *config.Config) string {
+	return fmt.Sprintf("Warning: %d cities configured, more than max_clocks (%d)", len(cfg.Cities), cfg.MaxClocks)
+}
+
+// oldTimezonesNotice returns the warning shown when the config uses old
+// timezone names, or "" if it doesn't
+func oldTimezonesNotice(cfg *config.Config) string {
+	old := cfg.OldTimezones()
+	if len(old) == 0 {
+		return ""
+	}
+	names := slices.Sorted(maps.Keys(old))
+	notice := fmt.Sprintf("Warning: %s is an old timezone name, use %s in the config", names[0], old[names[0]])
+	if len(names) > 1 {
+		notice = fmt.Sprintf("Warning: %d old timezone names in the config, e.g. %s is now %s", len(names), names[0], old[names[0]])
+	}
+	return notice
+}
+
+// buildClocks creates the clocks for all configured cities, in config order
+func buildClocks(cfg *config.Config) ([]*clock.Clock, error) {
+	calendar, err := clock.CalendarByName(cfg.Calendar)
+	if err != nil {
+		return nil, err
+	}
+
+	var clocks []*clock.Clock
+	for _, city := range cfg.Cities {
+		opts := clockOptions(cfg)
+		// Validated when the config is loaded
+		if start, end, err := cfg.CityWorkingHours(city); err == nil {
+			opts = append(opts, clock.WithWorkingHours(start, end))
+		}
+		clk, err := newCityClock(city, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create clock for %s: %w", city.Name, err)
+		}
+		clk.Icon = city.Icon
+		clk.Color = city.Color
+		clk.Short = city.Short
+		clk.Home = city.Home
+		clk.Calendar = calendar
+		for _, zone := range clk.Zones {
+			zone.Calendar = calendar
+		}
+		if city.Latitude != nil && city.Longitude != nil {
+			clk.Coordinates = &clock.Coordinates{Latitude: *city.Latitude, Longitude: *city.Longitude}
+		}
+		clocks = append(clocks, clk)
+	}
+	return clocks, nil
+}
+
+// clockOptions returns the clock options for the global display settings
+func clockOptions(cfg *config.Config) []clock.Option {
+	var opts []clock.Option
+	if cfg.TimeFormat == config.TimeFormat12h {
+		opts = append(opts, clock.With12Hour())
+	}
+	if cfg.HideWeekday {
+		opts = append(opts, clock.WithoutWeekday())
+	}
+	if cfg.ShowAbbrev {
+		opts = append(opts, clock.WithAbbrevOnly())
+	}
+	if window := cfg.DSTWarningWindow(); window > 0 {
+		opts = append(opts, clock.WithDSTWarning(window))
+	}
+	return opts
+}
+
+// newCityClock creates the clock for a city, with one sub-clock per zone for
+// multi-timezone cities
+func newCityClock(city config.City, opts ...clock.Option) (*clock.Clock, error) {
+	opts = append(opts, clock.WithFormat(city.Format))
+	if len(city.Zones) == 0 {
+		return clock.New(city.DisplayName(), city.Timezone, opts...)
+	}
+
+	var zones []*clock.Clock
+	for _, zone := range city.Zones {
+		clk, err := clock.New(zone.Name, zone.Timezone, opts...)
+		if err != nil {
+			return nil, err
+		}
+		zones = append(zones, clk)
+	}
+	return clock.NewMulti(city.DisplayName(), zones, opts...)
+}
+
+// moveClock moves the selected clock delta places in the manual order and
+// saves the new order
+func (m *model) moveClock(delta int) tea.Cmd {
+	if m.cfg.Sort != config.SortManual {
+		m.notice = "Set sort: manual to reorder clocks"
+		return nil
+	}
+	// In manual order the clocks are in the same order as the cities
+	from, to := m.cursor, m.cursor+delta
+	if from >= len(m.cfg.Cities) || to < 0 || to >= len(m.cfg.Cities) {
+		return nil
+	}
+	m.cfg.Cities[from], m.cfg.Cities[to] = m.cfg.Cities[to], m.cfg.Cities[from]
+	if err := m.cfg.Save(); err != nil {
+		m.err = err
+		return nil
+	}
+	cmd := m.reloadClocks()
+	m.cursor = to
+	m.scrollToCursor()
+	return cmd
+}
+
+// resort sorts the clocks again, keeping the selected clock selected
+func (m *model) resort() {
+	var selected *clock.Clock
+	if m.cursor < len(m.clocks) {
+		selected = m.clocks[m.cursor]
+	}
+	sortClocks(m.clocks, m.cfg.Sort, m.sortDesc, m.instant())
+	for i, clk := range m.clocks {
+		if clk == selected {
+			m.cursor = i
+		}
+	}
+}
+
+// sortCycle is the order in which O switches between the sort modes
+var sortCycle = []string{config.SortOffset, config.SortActiveFirst, config.SortName, config.SortManual}
+
+// cycleSort switches to the next sort mode and saves it. Switching to manual
+// keeps the clocks where they are by saving the cities in their current order
+func (m *model) cycleSort() {
+	next := sortCycle[(slices.Index(sortCycle, m.sortMode())+1)%len(sortCycle)]
+	if next == config.SortManual {
+		m.cfg.Cities = m.citiesInDisplayOrder()
+		m.cityClocks = slices.Clone(m.clocks)
+	}
+	m.cfg.Sort = next
+	if err := m.cfg.Save(); err != nil {
+		m.err = err
+		return
+	}
+	m.resort()
+	m.scrollToCursor()
+}
+
+// sortMode returns the configured sort mode, offset if none is set
+func (m model) sortMode() string {
+	if m.cfg.Sort == "" {
+		return config.SortOffset
+	}
+	return m.cfg.Sort
+}
+
+// sortClocks sorts clocks by UTC offset, west to east or east to west if desc
+// The active_first mode puts clocks within working hours first
+func sortClocks(clocks []*clock.Clock, mode string, desc bool, now time.Time) {
+	switch mode {
+	case config.SortManual:
+		// Keep the order of the config file
+		return
+	case config.SortName:
+		clock.SortByName(clocks, desc)
+		return
+	case config.SortActiveFirst:
+		clock.SortByWorkingHoursAt(clocks, now, desc)
+		return
+	}
+	clock.SortByUTCOffsetAt(clocks, now, desc)
+}
+
+// updateNow moves the clocks to the current time
+func (m *model) updateNow() {
+	m.now = clock.Now()
+	if m.cfg.Sort == config.SortActiveFirst {
+		// Clocks enter and leave working hours as time passes
+		m.resort()
+	}
+}
+
+// instant returns the time the clocks are sorted and rendered at: the last
+// tick, or the current time before the first one
+func (m model) instant() time.Time {
+	if m.now.IsZero() {
+		return clock.Now()
+	}
+	return m.now
+}
+
+// View renders the UI
+func (m model) View() string {
+	if m.err != nil {
+		return fmt.Sprintf("Error: %v\n\nPress 'q' to quit", m.err)
+	}
+
+	if m.quitting {
+		return "Goodbye!\n"
 	}
 
 	if !m.ready {
 		return "Initializing..."
 	}
 
-	switch m.state {
-	case viewMain:
-		return m.renderMain()
-	case viewAdd:
-		return m.renderAdd()
-	case viewDelete:
-		return m.renderDelete()
-	case viewConfirm:
-		return m.renderConfirm()
+	if m.tooSmall() {
+		return m.renderTooSmall()
+	}
+
+	switch m.state {
+	case viewMain, viewJump, viewFilter:
+		return m.renderMain()
+	case viewAdd:
+		return m.renderAdd()
+	case viewDelete:
+		return m.renderDelete()
+	case viewConfirm:
+		return m.renderConfirm()
+	case viewSuggest:
+		return m.renderSuggest()
+	case viewBrowse:
+		return m.renderBrowse()
+	case viewReorder:
+		return m.renderReorder()
+	case viewEdit:
+		return m.renderEdit()
+	case viewPlanner:
+		return m.renderPlanner()
+	case viewConvert:
+		return m.renderConvert()
+	case viewHelp:
+		return m.renderHelp()
+	}
+
+	return ""
+}
+
+// renderMain renders the main clock view
+func (m model) renderMain() string {
+	// Command bar
+	commandBar := m.renderCommandBar()
+
+	if header := m.headerLines(); len(header) > 0 {
+		return fmt.Sprintf("%s\n%s\n%s", strings.Join(header, "\n"), m.viewport.View(), commandBar)
+	}
+	return fmt.Sprintf("%s\n%s", m.viewport.View(), commandBar)
+}
+
+// headerLines returns the lines shown above the clock grid
+func (m model) headerLines() []string {
+	headerStyle := lipgloss.NewStyle().
+		Foreground(m.theme().date).
+		Padding(0, 1)
+
+	var lines []string
+	if m.cfg.ShowUnix && len(m.clocks) > 0 {
+		// The Unix timestamp is the same for every clock, so show it once
+		lines = append(lines, headerStyle.Render("Unix time: "+m.clocks[0].FormatUnix()))
+	}
+	if m.cfg.ShowOffsetSpan && len(m.clocks) > 0 {
+		// Computed on every render, so DST changes are picked up
+		minOffset, maxOffset := clock.OffsetSpan(m.clocks)
+		spread := time.Duration(maxOffset-minOffset) * time.Second
+		lines = append(lines, headerStyle.Render(fmt.Sprintf("span: %s to %s, %s spread",
+			clock.FormatShortOffset(minOffset), clock.FormatShortOffset(maxOffset), formatSpread(spread))))
+	}
+	return lines
+}
+
+// formatSpread formats a duration of whole minutes as e.g. "17h" or "9h45m"
+func formatSpread(d time.Duration) string {
+	hours := int(d.Hours())
+	minutes := int(d.Minutes()) % 60
+	if minutes != 0 {
+		return fmt.Sprintf("%dh%02dm", hours, minutes)
+	}
+	return fmt.Sprintf("%dh", hours)
+}
+
+// viewportHeight returns the height available to the clock grid
+func (m model) viewportHeight() int {
+	// Reserve space for the header and command bar (1 newline + 1 bar line)
+	// Tiny terminals would otherwise give the viewport a negative height
+	return max(m.height-2-len(m.headerLines()), 0)
+}
+
+// viewportWidth returns the width available to the clock grid
+func (m model) viewportWidth() int {
+	return max(m.width, 1)
+}
+
+// tooSmall reports whether the terminal is too small to render any view
+func (m model) tooSmall() bool {
+	return m.width < minTerminalWidth || m.height < minTerminalHeight
+}
+
+// renderTooSmall renders the notice shown instead of the UI on tiny terminals
+func (m model) renderTooSmall() string {
+	msg := fmt.Sprintf("Terminal too small (%dx%d)\nNeed at least %dx%d", m.width, m.height, minTerminalWidth, minTerminalHeight)
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, msg)
+}
+
+// renderAdd renders the add city view
+func (m model) renderAdd() string {
+	var b strings.Builder
+
+	// Title (without padding on short terminals to leave room for results)
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(m.theme().accent).
+		Padding(1, 0)
+	if m.height < compactAddViewHeight {
+		titleStyle = titleStyle.Padding(0)
+	}
+	b.WriteString(titleStyle.Render("Add City"))
+	b.WriteString("\n\n")
+
+	// Check if GeoNames is ready
+	if !m.geonamesDB.IsReady() {
+		if m.geonamesDB.GetError() != nil {
+			b.WriteString(fmt.Sprintf("Error loading city database: %v\n", m.geonamesDB.GetError()))
+		} else {
+			b.WriteString("Loading city database...\n")
+		}
+		b.WriteString("\n")
+		b.WriteString(lipgloss.NewStyle().Foreground(m.theme().dim).Render("Press ESC to cancel"))
+		return b.String()
+	}
+
+	// Why the city data couldn't be downloaded
+	if err := m.geonamesDB.GetError(); err != nil {
+		text := fmt.Sprintf("City data not updated: %v", err)
+		if m.geonamesDB.IsEmbedded() {
+			text = fmt.Sprintf("Only capital cities are available: %v", err)
+		}
+		b.WriteString(lipgloss.NewStyle().Foreground(m.theme().warning).Render(text))
+		b.WriteString("\n\n")
+	}
+
+	// Passive warning about old city data
+	if m.staleNotice != "" {
+		b.WriteString(lipgloss.NewStyle().Foreground(m.theme().warning).Render(m.staleNotice))
+		b.WriteString("\n\n")
+	}
+
+	// Search input
+	b.WriteString("Search city or country (min 3 characters or a country code like \"jp\", \"tokyo, jp\" filters by country):\n")
+	b.WriteString(m.searchInput.View())
+	b.WriteString("\n\n")
+
+	// Explain the inverted sign of POSIX-style Etc/GMT zones before they're added
+	if m.selectedResult < len(m.searchResults) {
+		if note := etcGMTNote(m.searchResults[m.selectedResult].Timezone); note != "" {
+			b.WriteString(lipgloss.NewStyle().Foreground(m.theme().warning).Render(note))
+			b.WriteString("\n\n")
+		}
+	}
+
+	// Results
+	// Country codes find cities with fewer than 3 characters, so whatever was
+	// found is shown, since Enter adds the first result
+	if len(m.searchResults) == 0 && len(m.searchInput.Value()) < 3 {
+		b.WriteString(lipgloss.NewStyle().Foreground(m.theme().dim).Render("Type at least 3 characters or a country code to search..."))
+	} else if len(m.searchResults) == 0 {
+		b.WriteString(lipgloss.NewStyle().Foreground(m.theme().dim).Render("No cities found"))
+	} else {
+		// Show as many results as fit between the input and the key hints:
+		// the lines used so far, the results header, and the blank line + hints
+		usedLines := lipgloss.Height(b.String()) - 1
+		maxVisible := listHeight(len(m.searchResults), min(maxAddResults, m.height-usedLines-3))
+
+		b.WriteString(fmt.Sprintf("Results (%d):\n", len(m.searchResults)))
+		start, end := visibleRange(m.selectedResult, len(m.searchResults), maxVisible)
+
+		b.WriteString(m.moreLine(start, "above"))
+		for i := start; i < end; i++ {
+			city := m.searchResults[i]
+			line := "  " + formatCityResult(city)
+			if city.CountryCode == "" {
+				// Timezone typed directly rather than a GeoNames city
+				line = fmt.Sprintf("  %s (timezone)", city.Timezone)
+			}
+
+			if i == m.selectedResult {
+				line = lipgloss.NewStyle().
+					Foreground(m.theme().accent).
+					Bold(true).
+					Render("> " + line)
+			}
+			b.WriteString(line)
+			b.WriteString("\n")
+		}
+		b.WriteString(m.moreLine(len(m.searchResults)-end, "below"))
+	}
+
+	b.WriteString("\n")
+	// Refreshing the city data, with its age while it isn't downloading
+	// Offline there is nothing to refresh
+	refresh := "Ctrl+R: Refresh Cities | "
+	if m.geonamesDB.IsOffline() {
+		refresh = ""
+	} else if m.geonamesDB.IsDownloading() {
+		refresh = "Refreshing Cities... | "
+	} else if !m.cacheUpdated.IsZero() {
+		refresh = "Ctrl+R: Refresh Cities (updated " + m.cacheUpdated.Format("2006-01-02") + ") | "
+	}
+	b.WriteString(lipgloss.NewStyle().Foreground(m.theme().dim).Render(m.glyphs().upDown + ": Navigate | Enter: Select | Tab: Browse | " + refresh + "ESC: Cancel"))
+
+	return b.String()
+}
+
+// listHeight returns how many of n list items fit in lines, leaving room for
+// the scroll indicators when not all of them fit. At least one item is shown
+func listHeight(n, lines int) int {
+	if n > lines {
+		lines -= 2
 	}
+	return max(lines, 1)
+}
 
-	return ""
+// visibleRange returns the range [start, end) of a list of n items to show
+// in maxVisible lines, scrolled so that cursor is visible
+func visibleRange(cursor, n, maxVisible int) (start, end int) {
+	if cursor >= maxVisible {
+		start = cursor - maxVisible + 1
+	}
+	return start, min(start+maxVisible, n)
 }
 
-// renderMain renders the main clock view
-func (m model) renderMain() string {
-	// Render clocks
-	content := renderClocks(m.clocks, m.width, m.viewport.Height)
-	m.viewport.SetContent(content)
+// moreLine returns the scroll indicator for count list items hidden in
+// direction, or "" if there are none
+func (m model) moreLine(count int, direction string) string {
+	if count <= 0 {
+		return ""
+	}
+	return lipgloss.NewStyle().Foreground(m.theme().dim).Render(fmt.Sprintf("  %d more %s", count, direction)) + "\n"
+}
 
-	// Command bar
-	commandBar := m.renderCommandBar()
+// formatCityResult formats a city for the search results, e.g.
+// "Portland, US-OR (pop 650k) America/Los_Angeles", leaving out what's unknown
+func formatCityResult(city geonames.City) string {
+	text := city.Name
+	if city.CountryCode != "" {
+		region := city.CountryCode
+		if city.Admin1 != "" {
+			region += "-" + city.Admin1
+		}
+		text += ", " + region
+	}
+	if city.Population > 0 {
+		text += " (pop " + formatPopulation(city.Population) + ")"
+	}
+	return text + " " + city.Timezone
+}
 
-	return fmt.Sprintf("%s\n%s", m.viewport.View(), commandBar)
+// formatPopulation formats a population compactly, e.g. "950", "650k", or
+// "3.4M"
+func formatPopulation(n int) string {
+	switch {
+	case n >= 1_000_000:
+		return strconv.FormatFloat(float64(n)/1_000_000, 'f', 1, 64) + "M"
+	case n >= 1000:
+		return strconv.Itoa(n/1000) + "k"
+	}
+	return strconv.Itoa(n)
 }
 
-// renderAdd renders the add city view
-func (m model) renderAdd() string {
+// renderDelete renders the delete city view
+func (m model) renderDelete() string {
 	var b strings.Builder
 
 	// Title
 	titleStyle := lipgloss.NewStyle().
 		Bold(true).
-		Foreground(lipgloss.Color("205")).
+		Foreground(m.theme().accent).
 		Padding(1, 0)
-	b.WriteString(titleStyle.Render("Add City"))
+	b.WriteString(titleStyle.Render("Delete Cities"))
+	b.WriteString("\n\n")
+	b.WriteString(m.deleteInput.View())
 	b.WriteString("\n\n")
 
-	// Check if GeoNames is ready
-	if !m.geonamesDB.IsReady() {
-		if m.geonamesDB.GetError() != nil {
-			b.WriteString(fmt.Sprintf("Error loading city database: %v\n", m.geonamesDB.GetError()))
+	// List as many cities as fit above the blank line and key hints,
+	// scrolled to keep the cursor visible
+	matches := m.deleteMatches()
+	if len(matches) == 0 {
+		b.WriteString(lipgloss.NewStyle().Foreground(m.theme().dim).Render("No cities match"))
+		b.WriteString("\n")
+	}
+	usedLines := lipgloss.Height(b.String()) - 1
+	maxVisible := listHeight(len(matches), m.height-usedLines-2)
+	start, end := visibleRange(m.deleteCursor, len(matches), maxVisible)
+
+	b.WriteString(m.moreLine(start, "above"))
+	for i := start; i < end; i++ {
+		city := m.deleteList[matches[i]]
+		isSelected := m.deleteSelected[matches[i]]
+		isCursor := i == m.deleteCursor
+
+		checkbox := " "
+		if isSelected {
+			checkbox = "x"
+		}
+		line := fmt.Sprintf("  [%s] %s", checkbox, city.DisplayName())
+
+		if isCursor {
+			line = lipgloss.NewStyle().
+				Foreground(m.theme().accent).
+				Bold(true).
+				Render("> " + line)
 		} else {
-			b.WriteString("Loading city database...\n")
+			line = "  " + line
 		}
+
+		b.WriteString(line)
 		b.WriteString("\n")
-		b.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render("Press ESC to cancel"))
-		return b.String()
 	}
+	b.WriteString(m.moreLine(len(matches)-end, "below"))
 
-	// Search input
-	b.WriteString("Search city (min 3 characters):\n")
-	b.WriteString(m.searchInput.View())
+	b.WriteString("\n")
+	cancel := "ESC: Cancel"
+	if m.deleteInput.Value() != "" {
+		cancel = "ESC: Clear Filter"
+	}
+	b.WriteString(lipgloss.NewStyle().Foreground(m.theme().dim).Render("Type: Filter | " + m.glyphs().upDown + ": Navigate | Tab: Toggle | Enter: Delete | " + cancel))
+
+	return b.String()
+}
+
+// renderBrowse renders the timezone browser
+func (m model) renderBrowse() string {
+	var b strings.Builder
+
+	// Title
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(m.theme().accent).
+		Padding(1, 0)
+	if m.height < compactAddViewHeight {
+		titleStyle = titleStyle.Padding(0)
+	}
+	b.WriteString(titleStyle.Render("Browse Timezones"))
 	b.WriteString("\n\n")
 
-	// Results
-	if len(m.searchInput.Value()) < 3 {
-		b.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render("Type at least 3 characters to search..."))
-	} else if len(m.searchResults) == 0 {
-		b.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render("No cities found"))
-	} else {
-		b.WriteString(fmt.Sprintf("Results (%d):\n", len(m.searchResults)))
-		// Show results (limit visible results)
-		maxVisible := 10
-		start := 0
-		if m.selectedResult >= maxVisible {
-			start = m.selectedResult - maxVisible + 1
+	location := m.browsePrefix
+	if location == "" {
+		location = "All regions"
+	}
+	b.WriteString(strings.TrimSuffix(location, "/") + ":\n")
+
+	// Show as many entries as fit above the blank line + hints
+	usedLines := lipgloss.Height(b.String()) - 1
+	maxVisible := max(m.height-usedLines-2, 1)
+	start := 0
+	if m.browseCursor >= maxVisible {
+		start = m.browseCursor - maxVisible + 1
+	}
+	end := min(start+maxVisible, len(m.browseEntries))
+
+	for i := start; i < end; i++ {
+		line := "  " + strings.ReplaceAll(m.browseEntries[i], "_", " ")
+		if i == m.browseCursor {
+			line = lipgloss.NewStyle().
+				Foreground(m.theme().accent).
+				Bold(true).
+				Render("> " + line)
+		} else {
+			line = "  " + line
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	b.WriteString(lipgloss.NewStyle().Foreground(m.theme().dim).Render(m.glyphs().upDown + ": Navigate | Enter: Open/Add | Left: Back | Tab/ESC: Search"))
+
+	return b.String()
+}
+
+// renderSuggest renders the nearby cities suggestion view
+func (m model) renderSuggest() string {
+	var b strings.Builder
+
+	// Title
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(m.theme().accent).
+		Padding(1, 0)
+	b.WriteString(titleStyle.Render("Add Nearby Cities"))
+	b.WriteString("\n\n")
+
+	b.WriteString(fmt.Sprintf("Other major cities in %s:\n", m.suggestions[0].CountryCode))
+
+	// List suggestions
+	for i, city := range m.suggestions {
+		checkbox := " "
+		if m.suggestSelected[i] {
+			checkbox = "x"
 		}
-		end := start + maxVisible
-		if end > len(m.searchResults) {
-			end = len(m.searchResults)
+		line := fmt.Sprintf("  [%s] %s", checkbox, formatCityResult(city))
+
+		if i == m.suggestCursor {
+			line = lipgloss.NewStyle().
+				Foreground(m.theme().accent).
+				Bold(true).
+				Render("> " + line)
+		} else {
+			line = "  " + line
 		}
 
-		for i := start; i < end; i++ {
-			city := m.searchResults[i]
-			line := fmt.Sprintf("  %s, %s (%s)", city.Name, city.CountryCode, city.Timezone)
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
 
-			if i == m.selectedResult {
+	b.WriteString("\n")
+	b.WriteString(lipgloss.NewStyle().Foreground(m.theme().dim).Render(m.glyphs().upDown + ": Navigate | Space: Toggle | Enter: Add Selected | ESC: Skip"))
+
+	return b.String()
+}
+
+// renderReorder renders the reorder view
+func (m model) renderReorder() string {
+	var b strings.Builder
+
+	// Title
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(m.theme().accent).
+		Padding(1, 0)
+	b.WriteString(titleStyle.Render("Reorder Clocks"))
+	b.WriteString("\n\n")
+
+	// List cities in their new order
+	for i, city := range m.reorderList {
+		line := fmt.Sprintf("  %2d. %s", i+1, city.DisplayName())
+
+		if i == m.reorderCursor {
+			line = lipgloss.NewStyle().
+				Foreground(m.theme().accent).
+				Bold(true).
+				Render("> " + line)
+		} else {
+			line = "  " + line
+		}
+
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	b.WriteString(lipgloss.NewStyle().Foreground(m.theme().dim).Render(m.glyphs().upDown + ": Select | Shift+" + m.glyphs().upDown + ": Move | Enter: Save | ESC: Cancel"))
+
+	return b.String()
+}
+
+// renderEdit renders the edit city view
+func (m model) renderEdit() string {
+	var b strings.Builder
+
+	// Title
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(m.theme().accent).
+		Padding(1, 0)
+	b.WriteString(titleStyle.Render("Edit " + m.editCity.DisplayName()))
+	b.WriteString("\n\n")
+
+	// Inputs
+	b.WriteString("Name:\n")
+	b.WriteString(m.editInputs[editName].View())
+	b.WriteString("\n\n")
+	b.WriteString("Label (optional, shown instead of the name):\n")
+	b.WriteString(m.editInputs[editLabel].View())
+	b.WriteString("\n\n")
+	if len(m.editCity.Zones) > 0 {
+		b.WriteString(lipgloss.NewStyle().Foreground(m.theme().dim).Render("Timezones: several zones, edit them in the config file"))
+	} else {
+		b.WriteString("Timezone (type a city to search):\n")
+		b.WriteString(m.editInputs[editTimezone].View())
+	}
+	b.WriteString("\n\n")
+
+	// Why saving failed
+	if m.editErr != "" {
+		b.WriteString(lipgloss.NewStyle().Foreground(m.theme().warning).Render(m.editErr))
+		b.WriteString("\n\n")
+	}
+
+	// Cities found for the timezone input
+	hints := "Tab: Next Field | Enter: Save | ESC: Cancel"
+	if m.editFocus == editTimezone && len(m.editResults) > 0 {
+		for i, city := range m.editResults[:min(len(m.editResults), maxAddResults)] {
+			line := "  " + formatCityResult(city)
+			if city.CountryCode == "" {
+				line = fmt.Sprintf("  %s (timezone)", city.Timezone)
+			}
+			if i == m.editSelected {
 				line = lipgloss.NewStyle().
-					Foreground(lipgloss.Color("205")).
+					Foreground(m.theme().accent).
 					Bold(true).
 					Render("> " + line)
 			}
 			b.WriteString(line)
 			b.WriteString("\n")
 		}
+		b.WriteString("\n")
+		hints = m.glyphs().upDown + ": Navigate | Enter: Use Timezone | Tab: Next Field | ESC: Cancel"
+	}
+
+	b.WriteString(lipgloss.NewStyle().Foreground(m.theme().dim).Render(hints))
+
+	return b.String()
+}
+
+// renderPlanner renders the meeting planner, showing the reference clock's
+// chosen hour in every clock's local time
+func (m model) renderPlanner() string {
+	var b strings.Builder
+
+	// Title
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(m.theme().accent).
+		Padding(1, 0)
+	b.WriteString(titleStyle.Render("Meeting Planner"))
+	b.WriteString("\n\n")
+
+	if m.plannerRef >= len(m.clocks) {
+		// The clocks were reloaded with fewer cities meanwhile
+		b.WriteString("No clocks to compare\n\n")
+		b.WriteString(lipgloss.NewStyle().Foreground(m.theme().dim).Render("ESC: Back"))
+		return b.String()
+	}
+	ref := m.clocks[m.plannerRef]
+	refTime := m.plannerTime.In(ref.Location)
+	b.WriteString(fmt.Sprintf("%s at %s %s\n\n", ref.Name, refTime.Format("Mon 2006-01-02"), ref.FormatTimeAt(m.plannerTime)))
+
+	nameWidth := 0
+	for _, clk := range m.clocks {
+		nameWidth = max(nameWidth, lipgloss.Width(clk.Name))
+	}
+
+	// One line per clock, marking the reference and the clocks within
+	// working hours at that moment
+	working := 0
+	refDate := time.Date(refTime.Year(), refTime.Month(), refTime.Day(), 0, 0, 0, 0, time.UTC)
+	lines := make([]string, len(m.clocks))
+	textWidth := 0
+	for i, clk := range m.clocks {
+		local := m.plannerTime.In(clk.Location)
+		indicator := m.glyphs().offHours
+		if clk.WorkingAt(m.plannerTime) {
+			working++
+			indicator = m.glyphs().working
+		}
+		lines[i] = fmt.Sprintf("%s %-*s  %s %s", indicator, nameWidth, clk.Name, local.Format("Mon"), clk.FormatTimeAt(m.plannerTime))
+		// Days apart from the reference, e.g. "+1 day" across the date line
+		localDate := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+		if days := int(localDate.Sub(refDate).Hours() / 24); days != 0 {
+			lines[i] += fmt.Sprintf(" (%+d day)", days)
+		}
+		textWidth = max(textWidth, lipgloss.Width(lines[i]))
+	}
+
+	// The timeline needs the cursor, the text, a gap, and the 24 hours
+	timeline := m.newPlannerTimeline(refTime)
+	showTimeline := 2+textWidth+2+plannerHours <= m.width
+	if showTimeline {
+		b.WriteString(strings.Repeat(" ", 2+textWidth+2) + timeline.axis() + "\n")
+	}
+
+	for i, clk := range m.clocks {
+		style := lipgloss.NewStyle().Foreground(m.theme().dim)
+		if clk.WorkingAt(m.plannerTime) {
+			style = lipgloss.NewStyle().Foreground(m.theme().working)
+		}
+		line := style.Render(lines[i])
+
+		if i == m.plannerRef {
+			line = lipgloss.NewStyle().Foreground(m.theme().accent).Bold(true).Render("> ") + line
+		} else {
+			line = "  " + line
+		}
+		if showTimeline {
+			line += strings.Repeat(" ", textWidth-lipgloss.Width(lines[i])+2) + timeline.bar(clk)
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	if showTimeline {
+		b.WriteString(strings.Repeat(" ", 2+textWidth+2) + timeline.bestMarkers() + "\n")
+	}
+
+	b.WriteString(fmt.Sprintf("\n%d of %d clocks within working hours\n", working, len(m.clocks)))
+	b.WriteString(timeline.bestSummary(ref.Name) + "\n\n")
+	b.WriteString(lipgloss.NewStyle().Foreground(m.theme().dim).Render(m.glyphs().leftRight + ": Hour | " + m.glyphs().upDown + ": Reference Clock | n: Now | ESC: Back"))
+
+	return b.String()
+}
+
+// renderConvert renders the time converter, listing the entered time in
+// every clock's timezone
+func (m model) renderConvert() string {
+	var b strings.Builder
+
+	// Title
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(m.theme().accent).
+		Padding(1, 0)
+	b.WriteString(titleStyle.Render("Convert Time"))
+	b.WriteString("\n\n")
+
+	from := m.convertClock()
+	if from == nil {
+		b.WriteString("No home or local timezone to convert from\n\n")
+		b.WriteString(lipgloss.NewStyle().Foreground(m.theme().dim).Render("ESC: Back"))
+		return b.String()
+	}
+	b.WriteString(fmt.Sprintf("Time in %s (%s):\n", from.Name, from.Location))
+	b.WriteString(m.convertInput.View())
+	b.WriteString("\n\n")
+
+	if strings.TrimSpace(m.convertInput.Value()) != "" {
+		at, err := parseTimeOfDay(m.convertInput.Value(), from.GetTime())
+		if err != nil {
+			b.WriteString(lipgloss.NewStyle().Foreground(m.theme().warning).Render(err.Error()))
+			b.WriteString("\n\n")
+		} else {
+			nameWidth := 0
+			for _, clk := range m.clocks {
+				nameWidth = max(nameWidth, lipgloss.Width(clk.Name))
+			}
+			fromDate := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)
+			for _, clk := range m.clocks {
+				local := clk.TimeAt(at)
+				line := fmt.Sprintf("%-*s  %s %s", nameWidth, clk.Name, local.Format("Mon"), clk.FormatTimeAt(at))
+				// Days apart from the entered time, e.g. "+1 day" across the date line
+				localDate := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+				if days := int(localDate.Sub(fromDate).Hours() / 24); days != 0 {
+					line += fmt.Sprintf(" (%+d day)", days)
+				}
+
+				indicator := m.glyphs().offHours
+				style := lipgloss.NewStyle().Foreground(m.theme().dim)
+				if clk.WorkingAt(at) {
+					indicator = m.glyphs().working
+					style = lipgloss.NewStyle().Foreground(m.theme().working)
+				}
+				b.WriteString("  " + style.Render(indicator+" "+line) + "\n")
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	b.WriteString(lipgloss.NewStyle().Foreground(m.theme().dim).Render("Type a time like 15:30 or 3pm | ESC: Back"))
+
+	return b.String()
+}
+
+// plannerHours is the number of hour columns in the planner timeline
+const plannerHours = 24
+
+// plannerTimeline lays out the day of the planner's reference clock as one
+// column per hour, so every clock's working hours line up on the same
+// instants whatever their UTC offset
+type plannerTimeline struct {
+	m        model
+	starts   [plannerHours]time.Time // Start of each hour column
+	selected int                     // Column of the planner time
+	overlap  [plannerHours]int       // Clocks within working hours per column
+	best     int                     // Highest overlap of any column
+}
+
+// newPlannerTimeline builds the timeline for the day of refTime, counting the
+// clocks within working hours in each hour
+func (m model) newPlannerTimeline(refTime time.Time) plannerTimeline {
+	t := plannerTimeline{m: m}
+	midnight := time.Date(refTime.Year(), refTime.Month(), refTime.Day(), 0, 0, 0, 0, refTime.Location())
+	for h := range t.starts {
+		t.starts[h] = midnight.Add(time.Duration(h) * time.Hour)
+		if !t.starts[h].After(m.plannerTime) {
+			t.selected = h
+		}
+		for _, clk := range m.clocks {
+			if clk.WorkingAt(t.starts[h]) {
+				t.overlap[h]++
+			}
+		}
+		t.best = max(t.best, t.overlap[h])
+	}
+	return t
+}
+
+// axis returns the hour labels above the bars, every six hours
+func (t plannerTimeline) axis() string {
+	axis := []rune(strings.Repeat(" ", plannerHours))
+	for h := 0; h < plannerHours; h += 6 {
+		copy(axis[h:], []rune(strconv.Itoa(t.starts[h].Hour())))
+	}
+	return lipgloss.NewStyle().Foreground(t.m.theme().dim).Render(string(axis))
+}
+
+// bar returns the clock's day as one cell per hour, shaded within working
+// hours, with the planner time highlighted
+func (t plannerTimeline) bar(clk *clock.Clock) string {
+	var b strings.Builder
+	for h, start := range t.starts {
+		cell := t.m.glyphs().freeHour
+		style := lipgloss.NewStyle().Foreground(t.m.theme().dim)
+		if clk.WorkingAt(start) {
+			cell = t.m.glyphs().workHour
+			style = style.Foreground(t.m.theme().working)
+		}
+		if h == t.selected {
+			style = style.Foreground(t.m.theme().accent).Bold(true)
+		}
+		b.WriteString(style.Render(cell))
+	}
+	return b.String()
+}
+
+// bestMarkers marks the columns where the most clocks are within working hours
+func (t plannerTimeline) bestMarkers() string {
+	if t.best == 0 {
+		return ""
+	}
+	var b strings.Builder
+	for _, count := range t.overlap {
+		if count == t.best {
+			b.WriteString(t.m.glyphs().bestHour)
+		} else {
+			b.WriteString(" ")
+		}
+	}
+	return lipgloss.NewStyle().Foreground(t.m.theme().accent).Render(b.String())
+}
+
+// bestSummary describes the first run of hours with the most clocks within
+// working hours, e.g. "Best overlap: 15:00-17:00 Berlin time, 3 of 4 clocks"
+func (t plannerTimeline) bestSummary(refName string) string {
+	if t.best == 0 {
+		return "No working hours overlap on this day"
 	}
+	first := slices.Index(t.overlap[:], t.best)
+	last := first
+	for last+1 < plannerHours && t.overlap[last+1] == t.best {
+		last++
+	}
+	end := t.starts[last].Add(time.Hour)
+	return fmt.Sprintf("Best overlap: %s-%s %s time, %d of %d clocks", t.starts[first].Format("15:04"), end.Format("15:04"), refName, t.best, len(t.m.clocks))
+}
 
-	b.WriteString("\n")
-	b.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render("↑/↓: Navigate | Enter: Select | ESC: Cancel"))
+// keyHelp describes what a key does in the main view
+type keyHelp struct {
+	keys   string
+	action string
+}
 
-	return b.String()
+// mainKeyHelp lists the keys of the main view for the help screen, keep it in
+// sync with handleMainKeys. An empty keys entry stands for the arrow keys
+var mainKeyHelp = []keyHelp{
+	{"", "Select a clock"},
+	{"f", "Find a clock by name or timezone"},
+	{"/", "Filter the clocks by name"},
+	{"a", "Add a city"},
+	{"e", "Edit the selected city"},
+	{"d", "Delete cities"},
+	{"m / r", "Reorder the clocks"},
+	{"< / >", "Move the selected clock (sort: manual)"},
+	{"p", "Meeting planner"},
+	{"F", "Freeze the clocks or let them run again"},
+	{"[ / ]", "Move the frozen time by an hour"},
+	{"{ / }", "Move the frozen time by 15 minutes"},
+	{"c", "Convert a time to every clock"},
+	{"O", "Switch the sort mode"},
+	{"o", "Reverse the sort direction"},
+	{"t", "Show city names or timezones"},
+	{"s", "Hide or show seconds"},
+	{"u", "Show or hide the Unix timestamp"},
+	{"PgUp / PgDn", "Scroll the clocks"},
+	{"R", "Reset the config to defaults"},
+	{"?", "This help"},
+	{"q / Ctrl+C", "Quit"},
 }
 
-// renderDelete renders the delete city view
-func (m model) renderDelete() string {
+// renderHelp renders the list of keys
+func (m model) renderHelp() string {
 	var b strings.Builder
 
 	// Title
 	titleStyle := lipgloss.NewStyle().
 		Bold(true).
-		Foreground(lipgloss.Color("205")).
+		Foreground(m.theme().accent).
 		Padding(1, 0)
-	b.WriteString(titleStyle.Render("Delete Cities"))
+	b.WriteString(titleStyle.Render("Keys"))
 	b.WriteString("\n\n")
 
-	// List cities
-	for i, cityName := range m.deleteList {
-		isSelected := m.deleteSelected[i]
-		isCursor := i == m.deleteCursor
-
-		checkbox := " "
-		if isSelected {
-			checkbox = "x"
-		}
-		line := fmt.Sprintf("  [%s] %s", checkbox, cityName)
-
-		if isCursor {
-			line = lipgloss.NewStyle().
-				Foreground(lipgloss.Color("205")).
-				Bold(true).
-				Render("> " + line)
-		} else {
-			line = "  " + line
+	keyStyle := lipgloss.NewStyle().Bold(true).Foreground(m.theme().key).Width(14)
+	for _, help := range mainKeyHelp {
+		keys := help.keys
+		if keys == "" {
+			keys = m.glyphs().arrows
 		}
-
-		b.WriteString(line)
-		b.WriteString("\n")
+		b.WriteString("  " + keyStyle.Render(keys) + help.action + "\n")
 	}
 
 	b.WriteString("\n")
-	b.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render("↑/↓: Navigate | Space: Toggle | Enter: Delete | ESC: Cancel"))
+	b.WriteString(lipgloss.NewStyle().Foreground(m.theme().dim).Render("Press any key to return"))
 
 	return b.String()
 }
@@ -520,14 +2592,14 @@ func (m model) renderConfirm() string {
 	// Title
 	titleStyle := lipgloss.NewStyle().
 		Bold(true).
-		Foreground(lipgloss.Color("205")).
+		Foreground(m.theme().accent).
 		Padding(1, 0)
 	b.WriteString(titleStyle.Render("Confirm"))
 	b.WriteString("\n\n")
 
 	b.WriteString(m.confirmMsg)
 	b.WriteString("\n\n")
-	b.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render("y: Yes | n/ESC: No"))
+	b.WriteString(lipgloss.NewStyle().Foreground(m.theme().dim).Render("y: Yes | n/ESC: No"))
 
 	return b.String()
 }
@@ -535,28 +2607,93 @@ func (m model) renderConfirm() string {
 // renderCommandBar renders the command bar at the bottom
 func (m model) renderCommandBar() string {
 	leftStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("240")).
-		Background(lipgloss.Color("235")).
+		Foreground(m.theme().barText).
+		Background(m.theme().barBackground).
 		Padding(0, 1)
 
 	rightStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("240")).
-		Background(lipgloss.Color("235")).
+		Foreground(m.theme().barText).
+		Background(m.theme().barBackground).
 		Padding(0, 1)
 
-	// Left side: commands
-	commands := "a: Add City | d: Delete Cities | q: Quit"
+	// Left side: commands, or the search prompt in jump mode
+	commands := m.glyphs().arrows + ": Select | f: Find | a: Add City | e: Edit City | d: Delete Cities | ?: Help | q: Quit"
+	hints := true
+	if m.state == viewJump {
+		hints = false
+		commands = m.jumpInput.View()
+		if m.jumpNoMatch {
+			commands += " (no match)"
+		}
+		commands += " | Enter: Next | ESC: Done"
+	}
+	if m.state == viewFilter || (m.state == viewMain && m.filter != "") {
+		hints = false
+		commands = "Filter: " + m.filter
+		if m.state == viewFilter {
+			commands = m.filterInput.View()
+		}
+		commands += fmt.Sprintf(" (%d of %d) | ", len(m.visibleClocks()), len(m.clocks))
+		if m.state == viewFilter {
+			commands += "Enter: Done | ESC: Clear"
+		} else {
+			commands += "/: Change | ESC: Clear"
+		}
+	}
 	leftContent := leftStyle.Render(commands)
+	if !m.frozen.IsZero() && m.state == viewMain && m.filter == "" {
+		// Show the frozen moment in local time with the keys to move it
+		commands = fmt.Sprintf("Frozen at %s local | [/]: -/+1h | {/}: -/+15m | F: Resume", m.frozen.Local().Format("Mon 15:04"))
+		leftContent = leftStyle.Foreground(m.theme().warning).Render(commands)
+		hints = false
+	}
+	if m.notice != "" && m.state == viewMain {
+		leftContent = leftStyle.Foreground(m.theme().warning).Render(m.notice)
+		hints = false
+	}
 
 	// Right side: GeoNames status
 	var status string
 	if m.geonamesReady {
 		status = "GeoNames: Ready"
+		if m.geonamesDB.IsEmbedded() {
+			status = "GeoNames: Offline (capitals only)"
+		}
 	} else {
-		spinner := spinnerFrames[m.spinnerFrame]
-		status = fmt.Sprintf("%s Loading GeoNames...", spinner)
+		frames := m.glyphs().spinner
+		spinner := frames[m.spinnerFrame%len(frames)]
+		status = fmt.Sprintf("%s Loading GeoNames %s...", spinner, m.geonamesDB.Dataset())
+		if m.geonamesDownloaded > 0 {
+			status = fmt.Sprintf("%s Downloading GeoNames %s... %s", spinner, m.geonamesDB.Dataset(), formatDownloadProgress(m.geonamesDownloaded, m.geonamesProgress))
+		}
+	}
+	settings := "Sort: " + m.sortMode()
+	if m.sortDesc && m.sortMode() != config.SortManual {
+		settings += " (reversed)"
+	}
+	if m.cfg.HideSeconds {
+		settings += " | Seconds hidden"
+	}
+	rightContent := rightStyle.Render(settings + " | " + status)
+
+	// Lines wider than the terminal are cut off, so when space is short drop
+	// the settings first, then the long key hints. The GeoNames status stays
+	if m.width > 0 {
+		fits := func() bool {
+			return lipgloss.Width(leftContent)+lipgloss.Width(rightContent) <= m.width
+		}
+		if !fits() {
+			rightContent = rightStyle.MaxWidth(m.width).Render(status)
+		}
+		if !fits() && hints {
+			leftContent = leftStyle.Render("?: Help | q: Quit")
+		}
+		if room := m.width - lipgloss.Width(rightContent); room <= 0 {
+			leftContent = ""
+		} else if !fits() {
+			leftContent = lipgloss.NewStyle().MaxWidth(room).Render(leftContent)
+		}
 	}
-	rightContent := rightStyle.Render(status)
 
 	// Calculate spacing to push right content to the right
 	leftWidth := lipgloss.Width(leftContent)
@@ -568,20 +2705,172 @@ func (m model) renderCommandBar() string {
 	spacing := strings.Repeat(" ", spacingWidth)
 
 	// Combine with background color
-	barStyle := lipgloss.NewStyle().Background(lipgloss.Color("235"))
+	barStyle := lipgloss.NewStyle().Background(m.theme().barBackground)
 	return barStyle.Render(leftContent + spacing + rightContent)
 }
 
-// spinnerFrames are the characters used for the loading animation
-var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+// glyphSet holds the decorative characters used by the renderers
+type glyphSet struct {
+	border    lipgloss.Border
+	spinner   []string // Frames of the loading animation
+	upDown    string   // Key hint for list navigation
+	leftRight string   // Key hint for moving through the planner hours
+	arrows    string   // Key hint for grid navigation
+	working   string   // Marks a clock within working hours
+	offHours  string   // Marks a clock outside working hours
+	ellipsis  string   // Marks truncated text
+	day       string   // Marks a clock where the sun is up
+	night     string   // Marks a clock where the sun is down
+	workHour  string   // Planner hour within working hours
+	freeHour  string   // Planner hour outside working hours
+	bestHour  string   // Marks the planner hours with the most overlap
+}
+
+// unicodeGlyphs are used on terminals with proper Unicode support
+var unicodeGlyphs = glyphSet{
+	border:    lipgloss.RoundedBorder(),
+	spinner:   []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"},
+	upDown:    "↑/↓",
+	leftRight: "←/→",
+	arrows:    "←/→/↑/↓",
+	working:   "●",
+	offHours:  "○",
+	ellipsis:  "…",
+	day:       "☀",
+	night:     "☾",
+	workHour:  "█",
+	freeHour:  "·",
+	bestHour:  "▲",
+}
+
+// asciiGlyphs are used in ASCII mode for terminals without Unicode support
+var asciiGlyphs = glyphSet{
+	border:    lipgloss.ASCIIBorder(),
+	spinner:   []string{"|", "/", "-", "\\"},
+	upDown:    "Up/Down",
+	leftRight: "Left/Right",
+	arrows:    "Arrows",
+	working:   "*",
+	offHours:  "-",
+	ellipsis:  "...",
+	day:       "D",
+	night:     "N",
+	workHour:  "#",
+	freeHour:  ".",
+	bestHour:  "^",
+}
+
+// glyphs returns the glyph set for the current display mode
+func (m model) glyphs() glyphSet {
+	if m.ascii {
+		return asciiGlyphs
+	}
+	return unicodeGlyphs
+}
+
+// termLacksUnicode reports whether the TERM environment variable names a
+// terminal that is unlikely to render Unicode decorations correctly
+func termLacksUnicode() bool {
+	switch os.Getenv("TERM") {
+	case "dumb", "linux", "vt52", "vt100", "vt102", "vt220", "ansi":
+		return true
+	}
+	return false
+}
+
+// theme holds the colors used by the renderers
+type theme struct {
+	accent        lipgloss.Color // Titles, list cursors, and the selected card
+	dim           lipgloss.Color // Key hints and other secondary text
+	date          lipgloss.Color // Date lines and the header above the clocks
+	warning       lipgloss.Color // Notices and errors
+	working       lipgloss.Color // Working-hours indicator
+	offHours      lipgloss.Color // Times outside working hours with business_hours_colors
+	key           lipgloss.Color // Card titles and keys in the help screen
+	border        lipgloss.Color // Card borders
+	weekend       lipgloss.Color // Card borders on a weekend
+	home          lipgloss.Color // Home card border
+	day           lipgloss.Color // Day border with day_night_border
+	night         lipgloss.Color // Night border with day_night_border
+	barText       lipgloss.Color // Command bar text
+	barBackground lipgloss.Color // Command bar background
+}
+
+// darkTheme is the palette for terminals with a dark background
+var darkTheme = theme{
+	accent:        lipgloss.Color("205"),
+	dim:           lipgloss.Color("240"),
+	date:          lipgloss.Color("241"),
+	warning:       lipgloss.Color("214"),
+	working:       lipgloss.Color("42"),
+	offHours:      lipgloss.Color("203"),
+	key:           lipgloss.Color("86"),
+	border:        lipgloss.Color("62"),
+	weekend:       lipgloss.Color("238"),
+	home:          lipgloss.Color("39"),
+	day:           lipgloss.Color("214"),
+	night:         lipgloss.Color("60"),
+	barText:       lipgloss.Color("240"),
+	barBackground: lipgloss.Color("235"),
+}
+
+// lightTheme is the palette for terminals with a light background, with
+// darker text so hints stay readable
+var lightTheme = theme{
+	accent:        lipgloss.Color("162"),
+	dim:           lipgloss.Color("238"),
+	date:          lipgloss.Color("237"),
+	warning:       lipgloss.Color("166"),
+	working:       lipgloss.Color("28"),
+	offHours:      lipgloss.Color("160"),
+	key:           lipgloss.Color("30"),
+	border:        lipgloss.Color("61"),
+	weekend:       lipgloss.Color("250"),
+	home:          lipgloss.Color("25"),
+	day:           lipgloss.Color("172"),
+	night:         lipgloss.Color("60"),
+	barText:       lipgloss.Color("238"),
+	barBackground: lipgloss.Color("253"),
+}
+
+// theme returns the palette chosen by the theme setting
+func (m model) theme() theme {
+	switch m.cfg.Theme {
+	case config.ThemeLight:
+		return lightTheme
+	case config.ThemeAuto:
+		if termHasLightBackground() {
+			return lightTheme
+		}
+	}
+	return darkTheme
+}
+
+// termHasLightBackground reports whether $COLORFGBG, set by terminals such as
+// rxvt and Konsole as "foreground;background", names a light background color
+func termHasLightBackground() bool {
+	fields := strings.Split(os.Getenv("COLORFGBG"), ";")
+	background, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil {
+		return false
+	}
+	// White and the bright colors other than dark gray
+	return background == 7 || (background >= 9 && background <= 15)
+}
 
-// tickCmd returns a command that sends a tick message every second
+// tickCmd returns a command that sends a tick message on the next second
+// boundary, so the displayed seconds flip with the wall clock and don't drift
 func tickCmd() tea.Cmd {
-	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
+	return tea.Tick(untilNextSecond(time.Now()), func(t time.Time) tea.Msg {
 		return tickMsg(t)
 	})
 }
 
+// untilNextSecond returns the delay from now to the start of the next second
+func untilNextSecond(now time.Time) time.Duration {
+	return now.Truncate(time.Second).Add(time.Second).Sub(now)
+}
+
 // spinnerTickCmd returns a command that sends a spinner tick message
 func spinnerTickCmd() tea.Cmd {
 	return tea.Tick(100*time.Millisecond, func(t time.Time) tea.Msg {
@@ -589,37 +2878,76 @@ func spinnerTickCmd() tea.Cmd {
 	})
 }
 
-// checkGeoNamesCmd checks if GeoNames database is ready
-func checkGeoNamesCmd(db *geonames.Database) tea.Cmd {
+// formatDownloadProgress formats download progress as a percentage, e.g.
+// "42%", or in megabytes ("4.2 MB") if the size of the download is unknown
+func formatDownloadProgress(downloaded int64, progress float64) string {
+	if progress < 0 {
+		const mb = 1024 * 1024
+		return fmt.Sprintf("%.1f MB", float64(downloaded)/mb)
+	}
+	return fmt.Sprintf("%d%%", int(progress*100))
+}
+
+// searchDebounceCmd returns a command that asks for the search to run once
+// typing has paused for searchDebounce
+func searchDebounceCmd(seq int) tea.Cmd {
+	return tea.Tick(searchDebounce, func(time.Time) tea.Msg {
+		return searchDebounceMsg{seq: seq}
+	})
+}
+
+// checkGeoNamesCmd checks if GeoNames database is ready, reporting download
+// progress beyond the given number of bytes
+func checkGeoNamesCmd(db *geonames.Database, downloaded int64) tea.Cmd {
 	return func() tea.Msg {
-		// Check periodically until ready
+		// Check periodically until ready, progress restarts the wait
 		for i := 0; i < 300; i++ { // Check for up to 5 minutes
 			time.Sleep(100 * time.Millisecond)
-			if db.IsReady() {
-				return geonamesReadyMsg{}
-			}
 			if err := db.GetError(); err != nil {
 				return geonamesErrorMsg{err: err}
 			}
+			// The bundled capitals are ready first, keep reporting progress
+			// until the full dataset has been downloaded
+			if db.IsReady() && !db.IsDownloading() {
+				return geonamesReadyMsg{}
+			}
+			if n, _ := db.DownloadedBytes(); n != downloaded {
+				return progressMsg{downloaded: n, progress: db.Progress()}
+			}
 		}
 		return geonamesErrorMsg{err: fmt.Errorf("timeout waiting for GeoNames database")}
 	}
 }
 
 // renderClocks renders all clocks in a grid layout
-func renderClocks(clocks []*clock.Clock, width, height int) string {
-	if len(clocks) == 0 {
+func (m model) renderClocks(width, height int) string {
+	helpStyle := lipgloss.NewStyle().
+		Foreground(m.theme().dim).
+		Align(lipgloss.Center).
+		Padding(2, 4)
+	if len(m.clocks) == 0 {
 		// Show helpful message when no clocks are configured
-		helpStyle := lipgloss.NewStyle().
-			Foreground(lipgloss.Color("240")).
-			Align(lipgloss.Center).
-			Padding(2, 4)
 		return helpStyle.Render("Press 'a' to add a new city")
 	}
+	if len(m.visibleClocks()) == 0 {
+		return helpStyle.Render(fmt.Sprintf("No clocks match '%s', press ESC to show all", m.filter))
+	}
+
+	return strings.Join(m.clockRows(width), "\n")
+}
+
+// clockRows renders the clock cards and arranges them into grid rows, or
+// renders one row per clock in list layout
+func (m model) clockRows(width int) []string {
+	clocks := m.visibleClocks()
+	now := m.instant()
+	if m.cfg.Layout == config.LayoutList {
+		return m.clockLines(clocks, now, width)
+	}
 
 	// Calculate grid dimensions
 	numClocks := len(clocks)
-	cols := calculateColumns(clocks, width)
+	cols := m.gridColumns(clocks, width)
 	rows := (numClocks + cols - 1) / cols // Ceiling division
 
 	// No global padding - cards handle their own margins
@@ -632,14 +2960,24 @@ func renderClocks(clocks []*clock.Clock, width, height int) string {
 
 	// Content width (what we pass to renderClockCard)
 	cardWidth := widthPerCard - cardOverhead
-	if cardWidth < 20 {
-		cardWidth = 20 // Minimum width for readability
+	if minWidth := m.minCardContentWidth(clocks, now); cardWidth < minWidth {
+		cardWidth = minWidth // Minimum width for readability
+	}
+
+	// Cards warn of upcoming DST changes on an extra line, which all cards
+	// get when any does so they keep the same height
+	// The clocks look as far ahead as the config's dst_warning_days
+	dstWarnings := make(map[*clock.Clock]string)
+	for _, clk := range clocks {
+		if warning := clk.At(now).FormatDSTWarning(); warning != "" {
+			dstWarnings[clk] = warning
+		}
 	}
 
 	// Create clock cards
 	var clockCards []string
 	for _, clk := range clocks {
-		clockCards = append(clockCards, renderClockCard(clk, cardWidth))
+		clockCards = append(clockCards, m.renderClockCard(clk, now, cardWidth, dstWarnings))
 	}
 
 	// Arrange cards in grid - no global padding, cards handle their own margins
@@ -659,66 +2997,420 @@ func renderClocks(clocks []*clock.Clock, width, height int) string {
 		}
 	}
 
-	return strings.Join(rows_content, "\n")
+	return rows_content
+}
+
+// gridColumns returns the number of clocks per row: one in list layout,
+// otherwise as many as calculateColumns fits
+func (m model) gridColumns(clocks []*clock.Clock, width int) int {
+	if m.cfg.Layout == config.LayoutList {
+		return 1
+	}
+	return calculateColumns(len(clocks), width, m.cfg.Columns, m.minCardContentWidth(clocks, m.instant()))
+}
+
+// clockLines renders each clock as a compact line without borders, e.g.
+// "LONDON  14:03:22  UTC+00:00  Wed", with the names padded to one column
+// Multi-timezone clocks take a line per zone. All lines read the instant now
+func (m model) clockLines(clocks []*clock.Clock, now time.Time, width int) []string {
+	names := make([]string, len(clocks))
+	nameWidth := 0
+	for i, clk := range clocks {
+		names[i] = strings.ToUpper(clk.Name)
+		if m.cfg.ShowTimezone && len(clk.Zones) == 0 {
+			names[i] = clk.Location.String()
+		}
+		if clk.Icon != "" {
+			names[i] = clk.Icon + " " + names[i]
+		}
+		nameWidth = max(nameWidth, lipgloss.Width(names[i]))
+	}
+
+	base := lipgloss.NewStyle()
+	colors := cardColors{title: m.theme().key, time: m.theme().accent, date: m.theme().date}
+	lineStyle := lipgloss.NewStyle().MaxWidth(width)
+	dateStyle := lipgloss.NewStyle().Foreground(colors.date)
+
+	var lines []string
+	for i, clk := range clocks {
+		nameStyle := lipgloss.NewStyle().Foreground(colors.title).Width(nameWidth)
+		prefix := "  "
+		if m.cursor < len(m.clocks) && m.clocks[m.cursor] == clk {
+			nameStyle = nameStyle.Foreground(m.theme().accent).Bold(true)
+			prefix = lipgloss.NewStyle().Foreground(m.theme().accent).Bold(true).Render("> ")
+		}
+
+		at := clk.At(now)
+		zones := at.Zones
+		if len(zones) == 0 {
+			zones = []*clock.Clock{at}
+		}
+		var rows []string
+		for j, zone := range zones {
+			name := names[i]
+			if j > 0 {
+				name = ""
+			}
+			label := ""
+			if len(clk.Zones) > 0 {
+				label = zone.Name + " "
+			}
+			// Plain times take the time color here, since timeText only
+			// colors them when working hours are shown
+			timeText := m.timeText(zone, label, base, colors)
+			if !m.cfg.ShowWorkingHours && !m.cfg.BusinessHoursColors {
+				timeText = base.Bold(true).Foreground(colors.time).Render(timeText)
+			}
+			details := zone.FormatOffsetLabel()
+			if !zone.HideWeekday {
+				details += "  " + zone.FormatWeekday()
+			}
+			line := prefix + nameStyle.Render(name) + "  " + timeText + "  " + dateStyle.Render(details)
+			rows = append(rows, lineStyle.Render(line))
+			prefix = "  "
+		}
+		lines = append(lines, strings.Join(rows, "\n"))
+	}
+	return lines
 }
 
-// renderClockCard renders a single clock card
-func renderClockCard(clk *clock.Clock, width int) string {
+// renderClockCard renders a single clock card at the instant now, with a DST
+// warning line when any of the clocks has one
+func (m model) renderClockCard(clk *clock.Clock, now time.Time, width int, dstWarnings map[*clock.Clock]string) string {
+	// The card is told apart by its clock, then every line is read from a copy
+	// at the instant the whole grid is rendered at
+	selected := m.cursor < len(m.clocks) && m.clocks[m.cursor] == clk
+	offsets := m.offsetsLine(clk, now)
+	dstWarning := dstWarnings[clk]
+	clk = clk.At(now)
+
+	// Theme colors, replaced by the sun tint when enabled
+	base := lipgloss.NewStyle()
+	colors := cardColors{title: m.theme().key, time: m.theme().accent, date: m.theme().date}
+	if m.cfg.SunTint {
+		colors = sunTints[clk.SunPhase()]
+		base = base.Background(colors.background).MarginBackground(colors.background)
+	}
+
 	// Define styles
-	titleStyle := lipgloss.NewStyle().
+	titleStyle := base.
 		Bold(true).
-		Foreground(lipgloss.Color("86")).
+		Foreground(colors.title).
 		Align(lipgloss.Center).
 		Width(width).
 		PaddingTop(1).
 		PaddingBottom(1)
 
-	timeStyle := lipgloss.NewStyle().
+	timeStyle := base.
 		Bold(true).
-		Foreground(lipgloss.Color("205")).
+		Foreground(colors.time).
 		Align(lipgloss.Center).
 		Width(width).
 		MarginBottom(1)
 
-	dateStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("241")).
+	dateStyle := base.
+		Foreground(colors.date).
 		Align(lipgloss.Center).
 		Width(width).
 		PaddingBottom(1)
 
 	cardStyle := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("62")).
+		Border(m.glyphs().border).
+		BorderForeground(m.theme().border).
 		Padding(0, 2).
 		Margin(1, 1, 0, 1) // Top, Right, Bottom, Left margins
+	if m.cfg.SunTint {
+		cardStyle = cardStyle.Background(colors.background)
+	}
+
+	// Warm border by day, cool by night
+	if m.cfg.DayNightBorder {
+		cardStyle = cardStyle.BorderForeground(m.theme().night)
+		if clk.IsDaytime() {
+			cardStyle = cardStyle.BorderForeground(m.theme().day)
+		}
+	}
+
+	// The city's own color groups it with others, e.g. by team
+	if clk.Color != "" {
+		cardStyle = cardStyle.BorderForeground(lipgloss.Color(clk.Color))
+		titleStyle = titleStyle.Foreground(lipgloss.Color(clk.Color))
+	}
+
+	// Mute the border where it's the weekend, checked on every render so it
+	// changes as midnight passes in that timezone
+	if m.cfg.WeekendHighlighting() && clk.IsWeekend() {
+		cardStyle = cardStyle.BorderForeground(m.theme().weekend)
+	}
+
+	// Make the home card stand out
+	if clk.Home {
+		cardStyle = cardStyle.BorderForeground(m.theme().home)
+	}
+
+	// Highlight the selected card
+	if selected {
+		cardStyle = cardStyle.BorderForeground(m.theme().accent)
+	}
 
 	// Build card content with visual spacing
-	title := titleStyle.Render(strings.ToUpper(clk.Name))
+	// The icon, the day/night glyph, and their separating spaces take part
+	// of the title width
+	iconWidth := 0
+	if clk.Icon != "" {
+		iconWidth = lipgloss.Width(clk.Icon) + 1
+	}
+	dayNight := ""
+	if m.cfg.ShowDayNight {
+		dayNight = m.glyphs().night
+		if clk.IsDaytime() {
+			dayNight = m.glyphs().day
+		}
+		iconWidth += lipgloss.Width(dayNight) + 1
+	}
 
-	timeStr := timeStyle.Render(clk.FormatTime())
+	titleText := strings.ToUpper(clk.Name)
+	if m.cfg.ShowTimezone {
+		titleText = clk.Location.String()
+	} else if m.cfg.AbbreviateNames && !selected {
+		// The selected card keeps the full name
+		titleText = m.abbreviateName(titleText, width-iconWidth)
+	}
+	if clk.Icon != "" {
+		titleText = clk.Icon + " " + titleText
+	}
+	if dayNight != "" {
+		titleText += " " + dayNight
+	}
+	title := titleStyle.Render(titleText)
 
-	dateStr := dateStyle.Render(clk.FormatDateWithOffset())
+	lines := []string{title}
 
-	content := lipgloss.JoinVertical(lipgloss.Left,
-		title,
-		timeStr,
-		dateStr,
-	)
+	// Multi-timezone cards stack a labelled time and date per zone
+	if len(clk.Zones) > 0 {
+		for _, zone := range clk.Zones {
+			lines = append(lines,
+				timeStyle.MarginBottom(0).Render(m.timeText(zone, zone.Name+" ", base, colors)),
+				dateStyle.Render(zone.FormatDateWithOffset()),
+			)
+		}
+		if m.cfg.ShowLocalEquivalent && m.localClock != nil {
+			localTime := m.formatLocalTime(clk)
+			lines = append(lines, dateStyle.Render("you "+localTime))
+		}
+		return cardStyle.Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+	}
+
+	timeText := m.timeText(clk, "", base, colors)
+	if m.cfg.ShowLocalEquivalent && m.localClock != nil {
+		// Local equivalent of the same instant, shown dimmed below the time
+		localStyle := base.
+			Foreground(colors.date).
+			Align(lipgloss.Center).
+			Width(width).
+			MarginBottom(1)
+		localTime := m.formatLocalTime(clk)
+		lines = append(lines,
+			timeStyle.MarginBottom(0).Render(timeText),
+			localStyle.Render("you "+localTime),
+		)
+	} else {
+		lines = append(lines, timeStyle.Render(timeText))
+	}
+
+	footer := []string{dateStyle.PaddingBottom(0).Render(clk.FormatDateWithOffset())}
+	if offsets != "" {
+		footer = append(footer, dateStyle.PaddingBottom(0).Render(offsets))
+	}
+	if len(dstWarnings) > 0 {
+		footer = append(footer, dateStyle.PaddingBottom(0).Foreground(m.theme().warning).Render(dstWarning))
+	}
+	// Only the last line keeps the padding above the border
+	footer[len(footer)-1] = base.PaddingBottom(1).Render(footer[len(footer)-1])
+	lines = append(lines, footer...)
+
+	content := lipgloss.JoinVertical(lipgloss.Left, lines...)
 
 	return cardStyle.Render(content)
 }
 
+// offsetsLine returns the card line with the clock's offset from the home
+// clock and the local timezone at now, or "" if neither is shown. The home
+// card is labelled instead, so all cards keep the same height
+func (m model) offsetsLine(clk *clock.Clock, now time.Time) string {
+	var offsets []string
+	if home := m.homeClock(); home != nil {
+		relative := "home"
+		if home != clk {
+			relative = clk.At(now).FormatRelativeOffset(home.At(now)) + " from home"
+		}
+		offsets = append(offsets, relative)
+	}
+	if m.cfg.ShowLocalOffset && m.localClock != nil {
+		offsets = append(offsets, clk.At(now).FormatRelativeOffset(m.localClock.At(now))+" from local")
+	}
+	return strings.Join(offsets, " | ")
+}
+
+// homeClock returns the clock marked as home, or nil if there is none
+func (m model) homeClock() *clock.Clock {
+	for _, clk := range m.clocks {
+		if clk.Home {
+			return clk
+		}
+	}
+	return nil
+}
+
+// formatTime returns the clock's time, with seconds unless they are hidden
+func (m model) formatTime(clk *clock.Clock) string {
+	return formatClockTime(clk, m.cfg.HideSeconds)
+}
+
+// formatLocalTime returns the local time shown below a clock, with seconds
+// unless they are hidden
+func (m model) formatLocalTime(clk *clock.Clock) string {
+	if m.cfg.HideSeconds {
+		return clk.FormatTimeShortIn(m.localClock.Location)
+	}
+	return clk.FormatTimeIn(m.localClock.Location)
+}
+
+// timeText returns the clock's time prefixed with label, with the
+// working-hours indicator in front when enabled
+func (m model) timeText(clk *clock.Clock, label string, base lipgloss.Style, colors cardColors) string {
+	text := label + m.formatTime(clk)
+	if !m.cfg.ShowWorkingHours && !m.cfg.BusinessHoursColors {
+		return text
+	}
+
+	// Working hours are checked in the clock's own timezone on every render,
+	// so the colors change as the hours and weekends pass
+	working := clk.InWorkingHours()
+	if !m.cfg.ShowWorkingHours {
+		return base.Bold(true).Foreground(m.businessHoursColor(working)).Render(text)
+	}
+
+	// Working-hours indicator; outside working hours the time is dimmed as
+	// well unless business hours are colored
+	indicator := base.Foreground(m.theme().dim).Render(m.glyphs().offHours)
+	timeColor := lipgloss.TerminalColor(m.theme().dim)
+	if working {
+		indicator = base.Foreground(m.theme().working).Render(m.glyphs().working)
+		timeColor = colors.time
+	}
+	if m.cfg.BusinessHoursColors {
+		timeColor = m.businessHoursColor(working)
+	}
+	// Style the time separately so the indicator's reset doesn't clear its colors
+	return indicator + base.Bold(true).Foreground(timeColor).Render(" "+text)
+}
+
+// businessHoursColor returns the time color for business_hours_colors
+func (m model) businessHoursColor(working bool) lipgloss.Color {
+	if working {
+		return m.theme().working
+	}
+	return m.theme().offHours
+}
+
+// abbreviateName shortens a name to fit within width by reducing leading words
+// to initials ("SAN FRANCISCO" -> "S. FRANCISCO") and truncating as a last resort
+func (m model) abbreviateName(name string, width int) string {
+	if lipgloss.Width(name) <= width {
+		return name
+	}
+
+	words := strings.Fields(name)
+	for i := 0; i < len(words)-1; i++ {
+		words[i] = string([]rune(words[i])[0]) + "."
+		if short := strings.Join(words, " "); lipgloss.Width(short) <= width {
+			return short
+		}
+	}
+
+	ellipsis := m.glyphs().ellipsis
+	short := []rune(strings.Join(words, " "))
+	for len(short) > 0 && lipgloss.Width(string(short))+lipgloss.Width(ellipsis) > width {
+		short = short[:len(short)-1]
+	}
+	return string(short) + ellipsis
+}
+
+// cardColors holds the colors of a clock card
+type cardColors struct {
+	background lipgloss.Color
+	title      lipgloss.Color
+	time       lipgloss.Color
+	date       lipgloss.Color
+}
+
+// sunTints are the card colors for each sun phase, with foregrounds chosen to
+// stay readable against each background
+var sunTints = map[clock.SunPhase]cardColors{
+	clock.SunNight: {
+		background: lipgloss.Color("17"),
+		title:      lipgloss.Color("86"),
+		time:       lipgloss.Color("213"),
+		date:       lipgloss.Color("248"),
+	},
+	clock.SunTwilight: {
+		background: lipgloss.Color("130"),
+		title:      lipgloss.Color("230"),
+		time:       lipgloss.Color("230"),
+		date:       lipgloss.Color("223"),
+	},
+	clock.SunDay: {
+		background: lipgloss.Color("153"),
+		title:      lipgloss.Color("24"),
+		time:       lipgloss.Color("125"),
+		date:       lipgloss.Color("238"),
+	},
+}
+
+// viewportKeyMap limits viewport scrolling to page keys so the arrow keys
+// remain free for moving the grid cursor
+func viewportKeyMap() viewport.KeyMap {
+	return viewport.KeyMap{
+		PageDown: key.NewBinding(key.WithKeys("pgdown")),
+		PageUp:   key.NewBinding(key.WithKeys("pgup")),
+	}
+}
+
+// minCardContentWidth returns the content width every card needs: the
+// minimum, or more when a time, date or offsets line of the clocks is wider
+// at now, so no line wraps and the cards of a row keep the same height
+func (m model) minCardContentWidth(clocks []*clock.Clock, now time.Time) int {
+	width := minClockContentWidth
+	for _, clk := range clocks {
+		width = max(width, lipgloss.Width(m.offsetsLine(clk, now)))
+		zones := clk.Zones
+		if len(zones) == 0 {
+			zones = []*clock.Clock{clk}
+		}
+		for _, zone := range zones {
+			// Room for the working-hours indicator and its space
+			line := zone.FormatTime() + "  "
+			if len(clk.Zones) > 0 {
+				line = zone.Name + " " + line
+			}
+			width = max(width, lipgloss.Width(line), lipgloss.Width(zone.FormatDateWithOffset()))
+		}
+	}
+	return width
+}
+
 // calculateColumns determines the number of columns based on terminal width
-func calculateColumns(clocks []*clock.Clock, width int) int {
-	numClocks := len(clocks)
+// A positive columns setting is used instead of fitting as many as possible,
+// but never more than fit or than there are clocks
+// minContentWidth is the card content width from minCardContentWidth, which
+// ensures the date line (e.g., "Thu 2025-12-04 - UTC+05:30"), 12-hour times
+// with working-hours indicator (e.g. "* 03:04:05 PM") and offsets always fit
+func calculateColumns(numClocks, width, columns, minContentWidth int) int {
 	if numClocks == 0 {
 		return 1
 	}
 
-	// Use the minimum content width constant
-	// This ensures the date line (e.g., "2025-12-04 - UTC+05:30") always fits
-	minContentWidth := minClockContentWidth
-
 	// Calculate minimum card width needed
 	// Account for: border (2), padding left/right (4), margins left/right (2)
 	// Total overhead per card: 8 characters
@@ -729,6 +3421,9 @@ func calculateColumns(clocks []*clock.Clock, width int) int {
 	if maxClocksPerRow < 1 {
 		maxClocksPerRow = 1
 	}
+	if columns > 0 {
+		maxClocksPerRow = min(maxClocksPerRow, columns)
+	}
 
 	// Return the smaller of: max that fits OR total clocks
 	// This ensures:
@@ -740,7 +3435,73 @@ func calculateColumns(clocks []*clock.Clock, width int) int {
 	return maxClocksPerRow // Need multiple rows
 }
 
+// runReset asks for confirmation on stdin, then resets the config to defaults
+func runReset() error {
+	path, err := config.Path()
+	if err != nil {
+		return fmt.Errorf("failed to get config path: %w", err)
+	}
+
+	fmt.Printf("Reset %s to defaults? The current file is backed up first. [y/N] ", path)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+		fmt.Println("Aborted")
+		return nil
+	}
+
+	backupPath, err := config.Reset()
+	if err != nil {
+		return err
+	}
+	if backupPath != "" {
+		fmt.Printf("Backed up previous config to %s\n", backupPath)
+	}
+	fmt.Printf("Wrote default config to %s\n", path)
+	return nil
+}
+
 func main() {
+	asciiFlag := flag.Bool("ascii", false, "use ASCII-only borders and glyphs (for limited terminals)")
+	watch := flag.Bool("watch", false, "run without a TUI, periodically writing the clocks to --output")
+	output := flag.String("output", "", "file to write to in --watch mode")
+	interval := flag.Duration("interval", time.Minute, "how often to write the output in --watch mode")
+	format := flag.String("format", formatText, "output format for --watch: text, json, or markdown")
+	exportMD := flag.Bool("export-md", false, "print the clocks as a markdown table and exit")
+	once := flag.Bool("once", false, "print the current time of each city and exit")
+	flag.BoolVar(once, "print", false, "alias for --once")
+	jsonOut := flag.Bool("json", false, "print the clocks as a JSON array and exit")
+	compact := flag.Bool("compact", false, "print the clocks on one line, e.g. \"LON 14:03 | NYC 09:03\", and exit")
+	noColor := flag.Bool("no-color", false, "render without colors")
+	offline := flag.Bool("offline", false, "search the bundled capital cities instead of downloading the GeoNames data")
+	reset := flag.Bool("reset", false, "back up the config and replace it with the defaults, after confirmation")
+	width := flag.Int("width", 0, "render at this width instead of the terminal's (for screenshots)")
+	height := flag.Int("height", 0, "render at this height instead of the terminal's (for screenshots)")
+	flag.Parse()
+
+	// "worldclock list" is the same as --once
+	if flag.Arg(0) == "list" {
+		*once = true
+	}
+	// NO_COLOR (https://no-color.org) does the same as --no-color when set
+	// to any non-empty value
+	if *noColor || os.Getenv("NO_COLOR") != "" {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+
+	if *width < 0 || *height < 0 {
+		fmt.Fprintln(os.Stderr, "Error: --width and --height must not be negative")
+		os.Exit(1)
+	}
+
+	// Reset before loading, the current config may not even parse
+	if *reset {
+		if err := runReset(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -748,23 +3509,85 @@ func main() {
 		os.Exit(1)
 	}
 
+	// The GeoNames environment overrides aren't validated with the file
+	if _, err := cfg.GeoNamesDataset(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if _, err := cfg.GeoNamesBaseURL(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Create clocks from config
-	var clocks []*clock.Clock
-	for _, city := range cfg.Cities {
-		clk, err := clock.New(city.Name, city.Timezone)
+	cityClocks, err := buildClocks(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	clocks := slices.Clone(cityClocks)
+	sortClocks(clocks, cfg.Sort, cfg.SortDesc, clock.Now())
+
+	// One-shot mode for scripts and status bars: print once and exit
+	if *once {
+		fmt.Print(renderText(clocks, cfg.HideSeconds))
+		return
+	}
+
+	// Single line for shell prompts and tmux status bars: print once and exit
+	if *compact {
+		fmt.Println(renderCompact(clocks))
+		return
+	}
+
+	// JSON export for jq or status bar modules: print once and exit
+	if *jsonOut {
+		data, err := renderJSON(clocks)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error creating clock for %s: %v\n", city.Name, err)
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
-		clocks = append(clocks, clk)
+		os.Stdout.Write(data)
+		return
+	}
+
+	// Markdown export: print once and exit
+	if *exportMD {
+		fmt.Print(renderMarkdown(clocks, cfg.HideSeconds))
+		return
+	}
+
+	// Headless mode: write the clocks to a file without starting the TUI
+	if *watch {
+		if err := runWatch(cfg, *output, *interval, *format); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
 	}
 
-	// Sort clocks by UTC offset (west to east)
-	clock.SortByUTCOffset(clocks)
+	// Clock for the system timezone, used to show local equivalents
+	localClock, err := clock.New("Local", config.GetSystemTimezone())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating local clock: %v\n", err)
+		os.Exit(1)
+	}
 
-	// Initialize GeoNames database (async)
-	geonamesDB := geonames.NewDatabase()
-	geonamesDB.LoadAsync()
+	// Initialize GeoNames database (async), or use the bundled capitals offline
+	var geonamesDB *geonames.Database
+	if *offline {
+		geonamesDB, err = geonames.NewEmbeddedDatabase()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		// Validated after the config is loaded
+		dataset, _ := cfg.GeoNamesDataset()
+		baseURL, _ := cfg.GeoNamesBaseURL()
+		geonamesDB = geonames.NewDatabase(dataset, baseURL)
+		geonamesDB.LoadAsync()
+	}
 
 	// Initialize search input
 	ti := textinput.New()
@@ -772,21 +3595,85 @@ func main() {
 	ti.CharLimit = 50
 	ti.Width = 50
 
+	// Initialize jump-to-city input
+	ji := textinput.New()
+	ji.Prompt = "Find: "
+	ji.CharLimit = 50
+	ji.Width = 20
+
+	// Initialize time converter input
+	ci := textinput.New()
+	ci.Placeholder = "15:30"
+	ci.CharLimit = 10
+	ci.Width = 10
+
+	// Initialize delete list filter input
+	di := textinput.New()
+	di.Prompt = "Filter: "
+	di.CharLimit = 50
+	di.Width = 20
+
+	// Initialize clock filter input
+	fi := textinput.New()
+	fi.Prompt = "Filter: "
+	fi.CharLimit = 50
+	fi.Width = 20
+
+	// ASCII mode: an explicit flag wins, otherwise use config or detect from TERM
+	ascii := cfg.ASCII || termLacksUnicode()
+	asciiForced := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "ascii" {
+			ascii = *asciiFlag
+			asciiForced = true
+		}
+	})
+
+	// Warn about old timezone names and configs exceeding the optional clock
+	// limit, which is the more pressing of the two
+	notice := oldTimezonesNotice(cfg)
+	if cfg.ExceedsMaxClocks() {
+		notice = maxClocksNotice(cfg)
+	}
+
+	// Remember when the config was loaded so later edits are picked up
+	configModTime, _ := config.ModTime()
+
 	// Initialize model
 	m := model{
 		cfg:            cfg,
 		clocks:         clocks,
+		cityClocks:     cityClocks,
+		sortDesc:       cfg.SortDesc,
+		localClock:     localClock,
 		geonamesDB:     geonamesDB,
 		state:          viewMain,
 		searchInput:    ti,
 		searchResults:  []geonames.City{},
 		selectedResult: 0,
 		deleteSelected: make(map[int]bool),
+		jumpInput:      ji,
+		filterInput:    fi,
+		deleteInput:    di,
+		convertInput:   ci,
+		ascii:          ascii,
+		asciiForced:    asciiForced,
+		fixedWidth:     *width,
+		fixedHeight:    *height,
+		notice:         notice,
+		configModTime:  configModTime,
 	}
 
 	// Run the program
-	p := tea.NewProgram(m, tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
+	opts := []tea.ProgramOption{tea.WithAltScreen()}
+	if cfg.FocusReporting() {
+		opts = append(opts, tea.WithReportFocus())
+	}
+	p := tea.NewProgram(m, opts...)
+	_, err = p.Run()
+	// Stop a download still running in the background
+	geonamesDB.Close()
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error running program: %v\n", err)
 		os.Exit(1)
 	}
@@ -0,0 +1,731 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/philtim/worldclock/clock"
+	"github.com/philtim/worldclock/config"
+	"github.com/philtim/worldclock/geonames"
+)
+
+// testCities are clocks in distinct timezones for building test models
+var testCities = []config.City{
+	{Name: "Los Angeles", Timezone: "America/Los_Angeles"},
+	{Name: "New York", Timezone: "America/New_York"},
+	{Name: "London", Timezone: "Europe/London"},
+	{Name: "Berlin", Timezone: "Europe/Berlin"},
+	{Name: "Moscow", Timezone: "Europe/Moscow"},
+	{Name: "Dubai", Timezone: "Asia/Dubai"},
+	{Name: "Kolkata", Timezone: "Asia/Kolkata"},
+	{Name: "Kathmandu", Timezone: "Asia/Kathmandu"},
+	{Name: "Singapore", Timezone: "Asia/Singapore"},
+	{Name: "Tokyo", Timezone: "Asia/Tokyo"},
+	{Name: "Sydney", Timezone: "Australia/Sydney"},
+	{Name: "Auckland", Timezone: "Pacific/Auckland"},
+}
+
+// useTempConfig points the config file at a temporary directory, so tests
+// never read or write the user's config
+func useTempConfig(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	path := filepath.Join(dir, "worldclock.yaml")
+	t.Setenv(config.ConfigEnv, path)
+	return path
+}
+
+// newTestModel returns a model showing the given cities in the main view,
+// like main builds it
+func newTestModel(t *testing.T, cities []config.City) model {
+	t.Helper()
+	useTempConfig(t)
+	cfg := &config.Config{Cities: cities}
+	cityClocks, err := buildClocks(cfg)
+	if err != nil {
+		t.Fatalf("buildClocks() error = %v", err)
+	}
+	clocks := slices.Clone(cityClocks)
+	sortClocks(clocks, cfg.Sort, false, clock.Now())
+	localClock, err := clock.New("Local", "UTC")
+	if err != nil {
+		t.Fatalf("clock.New() error = %v", err)
+	}
+	return model{
+		cfg:            cfg,
+		clocks:         clocks,
+		cityClocks:     cityClocks,
+		localClock:     localClock,
+		geonamesDB:     geonames.NewDatabase(geonames.DefaultDataset, ""),
+		geonamesReady:  true,
+		state:          viewMain,
+		searchInput:    textinput.New(),
+		jumpInput:      textinput.New(),
+		filterInput:    textinput.New(),
+		deleteInput:    textinput.New(),
+		convertInput:   textinput.New(),
+		deleteSelected: make(map[int]bool),
+	}
+}
+
+// updateModel passes the messages through Update in order
+func updateModel(m model, msgs ...tea.Msg) model {
+	for _, msg := range msgs {
+		next, _ := m.Update(msg)
+		m = next.(model)
+	}
+	return m
+}
+
+func TestViewportOffsetClamped(t *testing.T) {
+	pageDown := tea.KeyMsg{Type: tea.KeyPgDown}
+	scrolled := func(t *testing.T) model {
+		m := updateModel(newTestModel(t, testCities), tea.WindowSizeMsg{Width: 80, Height: 20})
+		for range len(testCities) {
+			m = updateModel(m, pageDown)
+		}
+		if m.viewport.YOffset == 0 {
+			t.Fatal("viewport didn't scroll, the test needs more clocks")
+		}
+		return m
+	}
+
+	t.Run("taller window", func(t *testing.T) {
+		m := updateModel(scrolled(t), tea.WindowSizeMsg{Width: 80, Height: 40})
+		if m.viewport.PastBottom() {
+			t.Errorf("YOffset = %d is past the bottom after the window grew", m.viewport.YOffset)
+		}
+	})
+
+	t.Run("fewer clocks", func(t *testing.T) {
+		m := scrolled(t)
+		m.clocks = m.clocks[:2]
+		m = updateModel(m, tickMsg{})
+		if m.viewport.YOffset != 0 {
+			t.Errorf("YOffset = %d, want 0 once all clocks fit", m.viewport.YOffset)
+		}
+	})
+}
+
+func TestTinyWindowShowsNotice(t *testing.T) {
+	for _, height := range []int{1, 0} {
+		m := newTestModel(t, testCities)
+		m = updateModel(m, tea.WindowSizeMsg{Width: 10, Height: height}, tickMsg{}, tea.KeyMsg{Type: tea.KeyPgDown})
+		if m.viewport.Height < 0 || m.viewport.Width < 0 {
+			t.Errorf("height %d: viewport is %dx%d, want no negative size", height, m.viewport.Width, m.viewport.Height)
+		}
+		if view := m.View(); !strings.Contains(view, "Terminal too small") {
+			t.Errorf("height %d: view lacks the too small notice:\n%s", height, view)
+		}
+	}
+}
+
+func TestUntilNextSecond(t *testing.T) {
+	second := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Duration
+	}{
+		{"on a second", second, time.Second},
+		{"just after a second", second.Add(time.Nanosecond), time.Second - time.Nanosecond},
+		{"999ms past a second", second.Add(999 * time.Millisecond), time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := untilNextSecond(tt.now); got != tt.want {
+				t.Errorf("untilNextSecond() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigReloadedOnTick(t *testing.T) {
+	m := newTestModel(t, testCities[:2])
+	if err := m.cfg.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	m.configModTime, _ = config.ModTime()
+	m = updateModel(m, tea.WindowSizeMsg{Width: 80, Height: 20})
+
+	// Edit the file as a user would, with a newer modification time
+	edited := &config.Config{Cities: testCities[2:5]}
+	if err := edited.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	path, _ := config.Path()
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	m = updateModel(m, tickMsg{})
+	if len(m.clocks) != 3 {
+		t.Fatalf("got %d clocks after the config changed, want 3", len(m.clocks))
+	}
+	if m.notice != "" {
+		t.Errorf("notice = %q, want none", m.notice)
+	}
+}
+
+// typeText returns the key presses that type s
+func typeText(s string) []tea.Msg {
+	var msgs []tea.Msg
+	for _, r := range s {
+		key := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}}
+		if r == ' ' {
+			// Terminals report the space bar as its own key
+			key.Type = tea.KeySpace
+		}
+		msgs = append(msgs, key)
+	}
+	return msgs
+}
+
+func TestFirstRunAddsFirstCity(t *testing.T) {
+	m := newTestModel(t, nil)
+	// The config directory doesn't exist yet either
+	path := filepath.Join(t.TempDir(), "config", "worldclock.yaml")
+	t.Setenv(config.ConfigEnv, path)
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(cfg.Cities) != 0 {
+		t.Fatalf("Load() returned %d cities without a config file, want 0", len(cfg.Cities))
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("config file exists before the first city is added: %v", err)
+	}
+
+	db, err := geonames.NewEmbeddedDatabase()
+	if err != nil {
+		t.Fatalf("NewEmbeddedDatabase() error = %v", err)
+	}
+	m.cfg = cfg
+	m.geonamesDB = db
+	m = updateModel(m, tea.WindowSizeMsg{Width: 80, Height: 24})
+	if view := m.View(); !strings.Contains(view, "Press 'a' to add a new city") {
+		t.Fatalf("first-run view lacks the add prompt:\n%s", view)
+	}
+
+	// Search for a city and add the first result
+	m = updateModel(m, typeText("a")...)
+	m = updateModel(m, typeText("Berlin")...)
+	m = updateModel(m, searchDebounceMsg{seq: m.searchSeq}, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.err != nil {
+		t.Fatalf("adding the city failed: %v", m.err)
+	}
+
+	saved, err := config.Load()
+	if err != nil {
+		t.Fatalf("Load() after adding error = %v", err)
+	}
+	if len(saved.Cities) != 1 || saved.Cities[0].Name != "Berlin" {
+		t.Fatalf("saved cities = %+v, want Berlin only", saved.Cities)
+	}
+	if len(m.clocks) != 1 {
+		t.Errorf("got %d clocks after adding the first city, want 1", len(m.clocks))
+	}
+}
+
+func TestCountryCodeSearchShowsResults(t *testing.T) {
+	m := newTestModel(t, nil)
+	db, err := geonames.NewEmbeddedDatabase()
+	if err != nil {
+		t.Fatalf("NewEmbeddedDatabase() error = %v", err)
+	}
+	m.geonamesDB = db
+	m = updateModel(m, tea.WindowSizeMsg{Width: 80, Height: 24})
+
+	// A two-letter country code lists the country's cities
+	m = updateModel(m, typeText("a")...)
+	m = updateModel(m, typeText("jp")...)
+	m = updateModel(m, searchDebounceMsg{seq: m.searchSeq})
+	if len(m.searchResults) == 0 {
+		t.Fatal("searching jp found no cities")
+	}
+	view := m.View()
+	if !strings.Contains(view, "Results (") || !strings.Contains(view, m.searchResults[0].Name) {
+		t.Errorf("view doesn't show the results for jp:\n%s", view)
+	}
+}
+
+// setNow makes the clocks read the given instant until the test ends
+func setNow(t *testing.T, now time.Time) {
+	t.Helper()
+	original := clock.Now
+	clock.Now = func() time.Time { return now }
+	t.Cleanup(func() { clock.Now = original })
+}
+
+// clockNames returns the names of clocks in order
+func clockNames(clocks []*clock.Clock) []string {
+	var names []string
+	for _, clk := range clocks {
+		names = append(names, clk.Name)
+	}
+	return names
+}
+
+func TestClocksSortedAndRenderedAtOneInstant(t *testing.T) {
+	// New York has moved to daylight time, London and Berlin haven't yet
+	setNow(t, time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC))
+	m := newTestModel(t, []config.City{
+		{Name: "Berlin", Timezone: "Europe/Berlin"},
+		{Name: "London", Timezone: "Europe/London"},
+		{Name: "New York", Timezone: "America/New_York"},
+	})
+	m = updateModel(m, tea.WindowSizeMsg{Width: 120, Height: 24}, tickMsg{})
+
+	var names []string
+	for _, clk := range m.clocks {
+		names = append(names, clk.Name)
+	}
+	if want := []string{"New York", "London", "Berlin"}; !slices.Equal(names, want) {
+		t.Fatalf("clocks = %v, want %v", names, want)
+	}
+
+	// The cards sit side by side, so their titles and offsets appear in
+	// the same order
+	view := m.View()
+	for _, want := range [][]string{{"NEW YORK", "LONDON", "BERLIN"}, {"UTC-04:00", "UTC+00:00", "UTC+01:00"}} {
+		last := -1
+		for _, s := range want {
+			i := strings.Index(view, s)
+			if i < 0 || i < last {
+				t.Fatalf("%q missing or out of order in the view:\n%s", s, view)
+			}
+			last = i
+		}
+	}
+}
+
+func TestSuggestionsAddedAllOrNothing(t *testing.T) {
+	m := newTestModel(t, testCities[:1])
+	m.cfg.MaxClocks = 2
+	if err := m.cfg.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	m = updateModel(m, tea.WindowSizeMsg{Width: 80, Height: 24})
+
+	// Only one of the two selected suggestions fits within max_clocks
+	m.state = viewSuggest
+	m.suggestions = []geonames.City{
+		{Name: "Sacramento", Timezone: "America/Los_Angeles", CountryCode: "US"},
+		{Name: "San Diego", Timezone: "America/Los_Angeles", CountryCode: "US"},
+	}
+	m.suggestSelected = map[int]bool{0: true, 1: true}
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyEnter})
+
+	if m.err != nil {
+		t.Fatalf("adding more cities than max_clocks allows locked the UI: %v", m.err)
+	}
+	if m.state != viewMain || !strings.Contains(m.notice, "limit of 2 clocks reached") {
+		t.Errorf("state = %v, notice = %q, want the main view with the max_clocks notice", m.state, m.notice)
+	}
+	if len(m.cfg.Cities) != 1 {
+		t.Errorf("config has %d cities after the failed add, want 1", len(m.cfg.Cities))
+	}
+	saved, err := config.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(saved.Cities) != 1 {
+		t.Errorf("saved config has %d cities after the failed add, want 1", len(saved.Cities))
+	}
+}
+
+func TestFailedToggleSaveReverted(t *testing.T) {
+	m := newTestModel(t, testCities[:1])
+	m = updateModel(m, tea.WindowSizeMsg{Width: 80, Height: 24})
+
+	// The config can't be written inside a regular file
+	blocker := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(blocker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(config.ConfigEnv, filepath.Join(blocker, "worldclock.yaml"))
+
+	m = updateModel(m, typeText("s")...)
+	if m.err != nil {
+		t.Fatalf("failed save locked the UI: %v", m.err)
+	}
+	if m.cfg.HideSeconds {
+		t.Error("HideSeconds stayed on after the failed save")
+	}
+	if !strings.Contains(m.notice, "Setting not saved") {
+		t.Errorf("notice = %q, want the failed save", m.notice)
+	}
+}
+
+func TestDeleteFilterWithSpaces(t *testing.T) {
+	m := newTestModel(t, []config.City{
+		{Name: "New York", Timezone: "America/New_York"},
+		{Name: "York", Timezone: "Europe/London"},
+		{Name: "Newcastle", Timezone: "Australia/Sydney"},
+	})
+	m = updateModel(m, tea.WindowSizeMsg{Width: 80, Height: 24})
+	m = updateModel(m, typeText("d")...)
+	m = updateModel(m, typeText("new york")...)
+
+	if got := m.deleteInput.Value(); got != "new york" {
+		t.Fatalf("filter = %q, want %q", got, "new york")
+	}
+	matches := m.deleteMatches()
+	if len(matches) != 1 || m.deleteList[matches[0]].Name != "New York" {
+		t.Fatalf("filter matches %v, want New York only", matches)
+	}
+	if len(m.deleteSelected) != 0 {
+		t.Errorf("typing a space selected cities: %v", m.deleteSelected)
+	}
+
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyTab})
+	if !m.deleteSelected[matches[0]] {
+		t.Error("Tab didn't select the matching city")
+	}
+}
+
+func TestFilterMatchesNameAndJumpStaysVisible(t *testing.T) {
+	m := newTestModel(t, []config.City{
+		{Name: "London", Label: "Office", Timezone: "Europe/London"},
+		{Name: "New York", Timezone: "America/New_York"},
+		{Name: "York", Timezone: "Europe/London"},
+	})
+	m = updateModel(m, tea.WindowSizeMsg{Width: 80, Height: 24})
+
+	// The labelled card is found by its city's name
+	m = updateModel(m, typeText("/")...)
+	m = updateModel(m, typeText("london")...)
+	if got := clockNames(m.visibleClocks()); !slices.Equal(got, []string{"Office"}) {
+		t.Errorf("filtering %q shows %v, want [Office]", "london", got)
+	}
+
+	// Jumping doesn't select a card the filter hides
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyEsc})
+	m = updateModel(m, typeText("/")...)
+	m = updateModel(m, typeText("york")...)
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyEnter})
+	m = updateModel(m, typeText("f")...)
+	m = updateModel(m, typeText("office")...)
+	if !m.jumpNoMatch {
+		t.Error("jump found the filtered out Office card")
+	}
+	if m.visibleIndex() < 0 {
+		t.Errorf("jump moved the cursor to hidden clock %q", m.clocks[m.cursor].Name)
+	}
+}
+
+func TestLabelledCopiesDeletedAndEditedApart(t *testing.T) {
+	m := newTestModel(t, []config.City{
+		{Name: "London", Timezone: "Europe/London"},
+		{Name: "New York", Label: "HQ", Timezone: "America/New_York"},
+		{Name: "New York", Label: "Client", Timezone: "America/New_York"},
+	})
+	m = updateModel(m, tea.WindowSizeMsg{Width: 80, Height: 24})
+
+	// Deleting HQ keeps the other copy of New York
+	m = updateModel(m, typeText("d")...)
+	m = updateModel(m, typeText("hq")...)
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyTab}, tea.KeyMsg{Type: tea.KeyEnter})
+	m = updateModel(m, typeText("y")...)
+	if m.err != nil {
+		t.Fatalf("deleting HQ failed: %v", m.err)
+	}
+	if got := clockNames(m.clocks); !slices.Equal(got, []string{"Client", "London"}) {
+		t.Fatalf("clocks after deleting HQ = %v, want [Client London]", got)
+	}
+
+	// Editing the remaining copy changes only it
+	m.cursor = slices.IndexFunc(m.clocks, func(clk *clock.Clock) bool { return clk.Name == "Client" })
+	m = updateModel(m, typeText("e")...)
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyTab}, tea.KeyMsg{Type: tea.KeyCtrlU})
+	m = updateModel(m, typeText("Customer")...)
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.editErr != "" || m.err != nil {
+		t.Fatalf("editing Client failed: %q %v", m.editErr, m.err)
+	}
+
+	saved, err := config.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	var names []string
+	for _, city := range saved.Cities {
+		names = append(names, city.DisplayName())
+	}
+	if !slices.Equal(names, []string{"London", "Customer"}) {
+		t.Errorf("saved cities = %v, want [London Customer]", names)
+	}
+}
+
+func TestClockCityWithSharedLabel(t *testing.T) {
+	m := newTestModel(t, []config.City{
+		{Name: "London", Label: "Office", Timezone: "Europe/London"},
+		{Name: "New York", Label: "Office", Timezone: "America/New_York"},
+	})
+	m = updateModel(m, tea.WindowSizeMsg{Width: 80, Height: 24})
+
+	// Sorted by offset, New York comes first
+	var names []string
+	for _, city := range m.citiesInDisplayOrder() {
+		names = append(names, city.Name)
+	}
+	if want := []string{"New York", "London"}; !slices.Equal(names, want) {
+		t.Errorf("citiesInDisplayOrder() = %v, want %v", names, want)
+	}
+
+	m.cursor = 1
+	m.startEdit()
+	if m.editCity.Name != "London" {
+		t.Errorf("editing the second clock edits %s, want London", m.editCity.Name)
+	}
+}
+
+func TestArrowsScrollAtTheEdges(t *testing.T) {
+	m := updateModel(newTestModel(t, testCities), tea.WindowSizeMsg{Width: 80, Height: 20})
+
+	// The cursor stays on the first row while paging down
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyPgDown})
+	offset := m.viewport.YOffset
+	if offset == 0 {
+		t.Fatal("viewport didn't scroll, the test needs more clocks")
+	}
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyUp})
+	if m.cursor != 0 || m.viewport.YOffset != offset-1 {
+		t.Errorf("up on the first row: cursor = %d, YOffset = %d, want 0 and %d", m.cursor, m.viewport.YOffset, offset-1)
+	}
+
+	// Likewise for the last row while paging up
+	for range len(testCities) {
+		m = updateModel(m, tea.KeyMsg{Type: tea.KeyDown})
+	}
+	last := m.cursor
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyPgUp})
+	offset = m.viewport.YOffset
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyDown})
+	if m.cursor != last || m.viewport.YOffset != offset+1 {
+		t.Errorf("down on the last row: cursor = %d, YOffset = %d, want %d and %d", m.cursor, m.viewport.YOffset, last, offset+1)
+	}
+}
+
+func TestViewportNotRenderedOnSpinnerTicks(t *testing.T) {
+	m := updateModel(newTestModel(t, testCities[:1]), tea.WindowSizeMsg{Width: 80, Height: 20})
+	view := m.viewport.View()
+
+	// Without a re-render the view keeps showing the one clock
+	m.clocks = nil
+	m = updateModel(m, spinnerTickMsg{})
+	if got := m.viewport.View(); got != view {
+		t.Error("a spinner tick re-rendered the clocks")
+	}
+	m = updateModel(m, tickMsg{})
+	if got := m.viewport.View(); got == view {
+		t.Error("a tick didn't re-render the clocks")
+	}
+}
+
+func TestCommandBarFitsWidth(t *testing.T) {
+	for _, width := range []int{80, 100, 120, 160} {
+		m := newTestModel(t, testCities[:3])
+		m.cfg.HideSeconds = true
+		m = updateModel(m, tea.WindowSizeMsg{Width: width, Height: 24})
+		m.geonamesReady = false
+		m.geonamesDownloaded = 5 << 20
+
+		bar := m.renderCommandBar()
+		if got := lipgloss.Width(bar); got > width {
+			t.Errorf("width %d: command bar is %d columns wide", width, got)
+		}
+		if !strings.Contains(bar, "Downloading GeoNames") {
+			t.Errorf("width %d: command bar %q lost the GeoNames status", width, bar)
+		}
+		if !strings.Contains(bar, "q: Quit") {
+			t.Errorf("width %d: command bar %q lost the quit hint", width, bar)
+		}
+	}
+}
+
+func TestFocusRedrawsCurrentTime(t *testing.T) {
+	setNow(t, time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC))
+	m := newTestModel(t, []config.City{{Name: "London", Timezone: "Europe/London"}})
+	m = updateModel(m, tea.WindowSizeMsg{Width: 80, Height: 24}, tickMsg{})
+
+	// Ticks pause while the terminal is in the background
+	setNow(t, time.Date(2024, 3, 15, 12, 5, 0, 0, time.UTC))
+	m = updateModel(m, tea.FocusMsg{})
+	if view := m.viewport.View(); !strings.Contains(view, "12:05") {
+		t.Errorf("view after regaining focus doesn't show 12:05:\n%s", view)
+	}
+}
+
+func TestWatchReloadsConfig(t *testing.T) {
+	path := useTempConfig(t)
+	cfg := &config.Config{Cities: testCities[2:3]}
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	clocks, err := buildClocks(cfg)
+	if err != nil {
+		t.Fatalf("buildClocks() error = %v", err)
+	}
+	modTime, _ := config.ModTime()
+
+	// touch gives the file a new modification time, writes within the same
+	// clock tick could otherwise go unnoticed
+	touch := func(mtime time.Time) {
+		t.Helper()
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	edited := &config.Config{Cities: testCities[9:10]}
+	if err := edited.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	touch(modTime.Add(time.Second))
+	cfg, clocks, modTime = reloadWatchConfig(cfg, clocks, modTime)
+	if names := clockNames(clocks); !slices.Equal(names, []string{"Tokyo"}) {
+		t.Errorf("clocks after the edit = %v, want [Tokyo]", names)
+	}
+
+	// A broken file keeps the last config
+	if err := os.WriteFile(path, []byte("cities: [\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	touch(modTime.Add(time.Second))
+	_, clocks, _ = reloadWatchConfig(cfg, clocks, modTime)
+	if names := clockNames(clocks); !slices.Equal(names, []string{"Tokyo"}) {
+		t.Errorf("clocks after a broken edit = %v, want [Tokyo]", names)
+	}
+}
+
+func TestOutputTimeFormats(t *testing.T) {
+	setNow(t, time.Date(2024, 3, 15, 14, 5, 9, 0, time.UTC))
+	cfg := &config.Config{
+		Cities:     []config.City{{Name: "London", Timezone: "Europe/London"}},
+		TimeFormat: config.TimeFormat12h,
+	}
+	clocks, err := buildClocks(cfg)
+	if err != nil {
+		t.Fatalf("buildClocks() error = %v", err)
+	}
+
+	// The human-readable formats follow the time settings
+	tests := []struct {
+		name   string
+		render func(hideSeconds bool) string
+	}{
+		{"text", func(hideSeconds bool) string { return renderText(clocks, hideSeconds) }},
+		{"markdown", func(hideSeconds bool) string { return renderMarkdown(clocks, hideSeconds) }},
+	}
+	for _, tt := range tests {
+		if got := tt.render(false); !strings.Contains(got, "02:05:09 PM") {
+			t.Errorf("%s output %q doesn't show 02:05:09 PM", tt.name, got)
+		}
+		if got := tt.render(true); !strings.Contains(got, "02:05 PM") || strings.Contains(got, ":09") {
+			t.Errorf("%s output with hidden seconds %q doesn't show 02:05 PM", tt.name, got)
+		}
+	}
+
+	// JSON shows the time like the clocks, seconds included
+	data, err := renderJSON(clocks)
+	if err != nil {
+		t.Fatalf("renderJSON() error = %v", err)
+	}
+	if !strings.Contains(string(data), `"time": "02:05:09 PM"`) {
+		t.Errorf("JSON output %s doesn't have the time 02:05:09 PM", data)
+	}
+}
+
+func TestCompactOutputAtOneInstant(t *testing.T) {
+	// Each reading of the time is a second later, crossing a minute
+	now := time.Date(2024, 3, 15, 14, 5, 59, 0, time.UTC)
+	original := clock.Now
+	clock.Now = func() time.Time {
+		at := now
+		now = now.Add(time.Second)
+		return at
+	}
+	t.Cleanup(func() { clock.Now = original })
+
+	clocks, err := buildClocks(&config.Config{Cities: []config.City{
+		{Name: "London", Timezone: "UTC"},
+		{Name: "Reykjavik", Timezone: "UTC"},
+	}})
+	if err != nil {
+		t.Fatalf("buildClocks() error = %v", err)
+	}
+	if got, want := renderCompact(clocks), "LON 14:05 | REY 14:05"; got != want {
+		t.Errorf("renderCompact() = %q, want %q", got, want)
+	}
+}
+
+func TestPlannerAndFreezeUseClockNow(t *testing.T) {
+	setNow(t, time.Date(2024, 3, 15, 12, 34, 56, 0, time.UTC))
+	m := newTestModel(t, []config.City{{Name: "London", Timezone: "Europe/London"}})
+	m = updateModel(m, tea.WindowSizeMsg{Width: 80, Height: 24}, tickMsg{})
+
+	m = updateModel(m, typeText("p")...)
+	want := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	if !m.plannerTime.Equal(want) {
+		t.Errorf("planner opened at %v, want %v", m.plannerTime, want)
+	}
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyRight}, tea.KeyMsg{Type: tea.KeyRight})
+	m = updateModel(m, typeText("n")...)
+	if !m.plannerTime.Equal(want) {
+		t.Errorf("planner returned to %v, want %v", m.plannerTime, want)
+	}
+
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyEsc})
+	m = updateModel(m, typeText("F")...)
+	if want := time.Date(2024, 3, 15, 12, 34, 0, 0, time.UTC); !m.frozen.Equal(want) {
+		t.Errorf("clocks frozen at %v, want %v", m.frozen, want)
+	}
+}
+
+func TestListLayoutShowsAbbrev(t *testing.T) {
+	setNow(t, time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	m := newTestModel(t, []config.City{{Name: "Berlin", Timezone: "Europe/Berlin"}})
+	m.cfg.Layout = config.LayoutList
+	m.cfg.ShowAbbrev = true
+	clocks, err := buildClocks(m.cfg)
+	if err != nil {
+		t.Fatalf("buildClocks() error = %v", err)
+	}
+	m.clocks, m.cityClocks = clocks, slices.Clone(clocks)
+	m = updateModel(m, tea.WindowSizeMsg{Width: 80, Height: 24})
+
+	// The list shows the abbreviation instead of the offset, like the cards
+	view := m.View()
+	if !strings.Contains(view, "CET") || strings.Contains(view, "UTC+01:00") {
+		t.Errorf("list view doesn't show CET instead of UTC+01:00:\n%s", view)
+	}
+}
+
+func TestCardsFitOffsetsLine(t *testing.T) {
+	setNow(t, time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC))
+	m := newTestModel(t, []config.City{
+		{Name: "Los Angeles", Timezone: "America/Los_Angeles", Home: true},
+		{Name: "London", Timezone: "Europe/London"},
+		{Name: "Kathmandu", Timezone: "Asia/Kathmandu"},
+	})
+	m.cfg.ShowLocalOffset = true
+	m = updateModel(m, tea.WindowSizeMsg{Width: 114, Height: 40}, tickMsg{})
+
+	// The longest offsets line stays on one line, so the cards of a row
+	// keep the same height
+	if view := m.View(); !strings.Contains(view, "+12h45m from home | +5h45m from local") {
+		t.Errorf("Kathmandu's offsets line wraps:\n%s", view)
+	}
+}
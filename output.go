@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/philtim/worldclock/clock"
+	"github.com/philtim/worldclock/config"
+)
+
+// Output formats for non-interactive rendering
+const (
+	formatText     = "text"
+	formatJSON     = "json"
+	formatMarkdown = "markdown"
+)
+
+// atOneInstant returns copies of the clocks all reading the current time, so
+// every row of the output shows the same instant
+func atOneInstant(clocks []*clock.Clock) []*clock.Clock {
+	now := clock.Now()
+	out := make([]*clock.Clock, 0, len(clocks))
+	for _, clk := range clocks {
+		out = append(out, clk.At(now))
+	}
+	return out
+}
+
+// snapshots captures the current state of all clocks
+func snapshots(clocks []*clock.Clock) []clock.Snapshot {
+	out := make([]clock.Snapshot, 0, len(clocks))
+	for _, clk := range atOneInstant(clocks) {
+		out = append(out, clk.Snapshot())
+	}
+	return out
+}
+
+// formatClockTime returns the clock's time in its configured format, without
+// the seconds if hideSeconds is set
+func formatClockTime(clk *clock.Clock, hideSeconds bool) string {
+	if hideSeconds {
+		return clk.FormatTimeShort()
+	}
+	return clk.FormatTime()
+}
+
+// renderText renders the clocks as an aligned plain-text table, with times
+// formatted like the TUI shows them
+// It does not use lipgloss so the output stays clean when piped
+func renderText(clocks []*clock.Clock, hideSeconds bool) string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	for _, clk := range atOneInstant(clocks) {
+		s := clk.Snapshot()
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.Name, formatClockTime(clk, hideSeconds), s.Date, s.UTCOffset)
+	}
+	w.Flush()
+	return b.String()
+}
+
+// renderCompact renders the clocks on a single line like
+// "LON 14:03 | NYC 09:03", using each clock's short code or the first three
+// letters of its name
+func renderCompact(clocks []*clock.Clock) string {
+	parts := make([]string, 0, len(clocks))
+	for _, clk := range atOneInstant(clocks) {
+		code := clk.Short
+		if code == "" {
+			code = shortCode(clk.Name)
+		}
+		parts = append(parts, code+" "+clk.FormatTimeShort())
+	}
+	return strings.Join(parts, " | ")
+}
+
+// shortCode returns the first three letters of name in upper case, skipping
+// spaces and punctuation ("St. Louis" -> "STL")
+func shortCode(name string) string {
+	var code []rune
+	for _, r := range name {
+		if unicode.IsLetter(r) {
+			code = append(code, unicode.ToUpper(r))
+			if len(code) == 3 {
+				break
+			}
+		}
+	}
+	return string(code)
+}
+
+// renderJSON renders the clocks as a JSON array
+func renderJSON(clocks []*clock.Clock) ([]byte, error) {
+	data, err := json.MarshalIndent(snapshots(clocks), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// renderMarkdown renders the clocks as a GitHub-flavored markdown table with
+// padded columns, so the source is readable as well as the rendered table
+func renderMarkdown(clocks []*clock.Clock, hideSeconds bool) string {
+	rows := [][]string{{"City", "Timezone", "Time", "UTC Offset"}}
+	for _, clk := range atOneInstant(clocks) {
+		s := clk.Snapshot()
+		rows = append(rows, []string{s.Name, s.Timezone, formatClockTime(clk, hideSeconds), s.UTCOffset})
+	}
+
+	// Escape pipes so names can't break the table, then measure columns
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			row[i] = strings.ReplaceAll(cell, "|", "\\|")
+			widths[i] = max(widths[i], utf8.RuneCountInString(row[i]), 3)
+		}
+	}
+
+	var b strings.Builder
+	writeRow := func(cells []string) {
+		b.WriteString("|")
+		for i, cell := range cells {
+			fmt.Fprintf(&b, " %s%s |", cell, strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)))
+		}
+		b.WriteString("\n")
+	}
+
+	writeRow(rows[0])
+	separator := make([]string, len(widths))
+	for i, w := range widths {
+		separator[i] = strings.Repeat("-", w)
+	}
+	writeRow(separator)
+	for _, row := range rows[1:] {
+		writeRow(row)
+	}
+	return b.String()
+}
+
+// renderOutput renders the clocks in the given output format
+// hideSeconds applies to the human-readable formats, JSON always has seconds
+func renderOutput(clocks []*clock.Clock, format string, hideSeconds bool) ([]byte, error) {
+	switch format {
+	case formatText:
+		return []byte(renderText(clocks, hideSeconds)), nil
+	case formatJSON:
+		return renderJSON(clocks)
+	case formatMarkdown:
+		return []byte(renderMarkdown(clocks, hideSeconds)), nil
+	}
+	return nil, fmt.Errorf("unknown output format '%s' (use %s, %s, or %s)", format, formatText, formatJSON, formatMarkdown)
+}
+
+// writeFileAtomic writes data to path via a temp file and rename, so readers
+// never see a partially written file
+func writeFileAtomic(path string, data []byte) error {
+	tempFile, err := os.CreateTemp(filepath.Dir(path), ".worldclock-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tempPath := tempFile.Name()
+
+	if _, err := tempFile.Write(data); err != nil {
+		tempFile.Close()
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tempFile.Close(); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+	return nil
+}
+
+// runWatch renders the clocks of cfg to path every interval until interrupted
+// Like the TUI's tick, every render re-sorts the clocks at the current time
+// and picks up edits to the config file
+func runWatch(cfg *config.Config, path string, interval time.Duration, format string) error {
+	if path == "" {
+		return fmt.Errorf("--watch requires --output")
+	}
+	if interval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+
+	// Stop cleanly on Ctrl-C or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	clocks, err := buildClocks(cfg)
+	if err != nil {
+		return err
+	}
+	modTime, _ := config.ModTime()
+	for {
+		cfg, clocks, modTime = reloadWatchConfig(cfg, clocks, modTime)
+		sortClocks(clocks, cfg.Sort, cfg.SortDesc, clock.Now())
+		data, err := renderOutput(clocks, format, cfg.HideSeconds)
+		if err != nil {
+			return err
+		}
+		if err := writeFileAtomic(path, data); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// reloadWatchConfig returns the config and clocks to watch: reloaded when the
+// config file changed since modTime, otherwise the current ones. A config
+// that fails to load keeps the current one, the file may be saved half-edited
+func reloadWatchConfig(cfg *config.Config, clocks []*clock.Clock, modTime time.Time) (*config.Config, []*clock.Clock, time.Time) {
+	newModTime, err := config.ModTime()
+	if err != nil || newModTime.Equal(modTime) {
+		return cfg, clocks, modTime
+	}
+
+	newCfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Config not reloaded: %v\n", err)
+		return cfg, clocks, newModTime
+	}
+	newClocks, err := buildClocks(newCfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Config not reloaded: %v\n", err)
+		return cfg, clocks, newModTime
+	}
+	return newCfg, newClocks, newModTime
+}